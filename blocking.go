@@ -0,0 +1,98 @@
+package bfq
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when waiting on a closed and drained BlockingQueue.
+var ErrClosed = errors.New("bfq: queue closed")
+
+// BlockingQueue is a concurrency-safe queue whose consumers block until
+// elements become available.
+type BlockingQueue[T any] struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	q      *Queue[T]
+	closed bool
+}
+
+// NewBlockingQueue creates an empty blocking queue.
+func NewBlockingQueue[T any]() *BlockingQueue[T] {
+	q := &BlockingQueue[T]{q: NewQueue[T]()}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Len returns the number of elements in the queue.
+func (q *BlockingQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.q.Len()
+}
+
+// PushBack inserts an element at the back and wakes waiting consumers.
+// It panics if the queue has been closed.
+func (q *BlockingQueue[T]) PushBack(v T) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		panic("bfq: push on closed queue")
+	}
+	q.q.PushBack(v)
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// PopFront removes and returns the front element, blocking until one is
+// available. Once the queue is closed and drained it returns false.
+func (q *BlockingQueue[T]) PopFront() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.q.IsEmpty() && !q.closed {
+		q.cond.Wait()
+	}
+	return q.q.PopFront()
+}
+
+// WaitNotEmpty blocks until at least one element is available without
+// consuming it. It returns ctx.Err() if ctx is done first, or ErrClosed if
+// the queue is closed and drained.
+func (q *BlockingQueue[T]) WaitNotEmpty(ctx context.Context) error {
+	// Wake the waiters when ctx is done so they can observe the cancellation.
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.q.IsEmpty() {
+		if q.closed {
+			return ErrClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.cond.Wait()
+	}
+	return nil
+}
+
+// Close marks the queue as closed, signalling that no more elements will be
+// pushed. Consumers drain the remaining elements, after which PopFront
+// returns false. Like closing a channel, pushing to or closing an already
+// closed queue panics.
+func (q *BlockingQueue[T]) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		panic("bfq: close of closed queue")
+	}
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
@@ -0,0 +1,117 @@
+package bfq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBlockingQueuePopFrontBlocks(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	done := make(chan int)
+	go func() {
+		v, _ := q.PopFront()
+		done <- v
+	}()
+
+	select {
+	case v := <-done:
+		t.Fatalf("Expected PopFront to block, got %v", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	q.PushBack(42)
+	if v := <-done; v != 42 {
+		t.Errorf("Expected popped value 42, got %v", v)
+	}
+}
+
+func TestBlockingQueueWaitNotEmpty(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	errc := make(chan error)
+	go func() {
+		errc <- q.WaitNotEmpty(context.Background())
+	}()
+
+	q.PushBack(1)
+	if err := <-errc; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Waiting must not consume the element
+	if q.Len() != 1 {
+		t.Errorf("Expected queue length 1, got %d", q.Len())
+	}
+	if err := q.WaitNotEmpty(context.Background()); err != nil {
+		t.Errorf("Unexpected error on non-empty queue: %v", err)
+	}
+}
+
+func TestBlockingQueueWaitNotEmptyCancel(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := q.WaitNotEmpty(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// An already-cancelled context returns immediately
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := q.WaitNotEmpty(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBlockingQueueClose(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	q.PushBack(1)
+	q.PushBack(2)
+	q.Close()
+
+	// Remaining elements are still delivered
+	for want := 1; want <= 2; want++ {
+		if val, ok := q.PopFront(); !ok || val != want {
+			t.Errorf("Expected popped value %d, got %v", want, val)
+		}
+	}
+
+	// A drained, closed queue no longer blocks
+	if val, ok := q.PopFront(); ok {
+		t.Errorf("Expected PopFront to return false after close, got %v", val)
+	}
+	if err := q.WaitNotEmpty(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestBlockingQueueCloseWakesConsumers(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, ok := q.PopFront()
+			done <- ok
+		}()
+	}
+
+	q.Close()
+	for i := 0; i < 4; i++ {
+		if ok := <-done; ok {
+			t.Errorf("Expected consumer to observe close")
+		}
+	}
+}
+
+func TestBlockingQueuePushAfterClosePanics(t *testing.T) {
+	q := NewBlockingQueue[int]()
+	q.Close()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected PushBack on closed queue to panic")
+		}
+	}()
+	q.PushBack(1)
+}
@@ -0,0 +1,340 @@
+package bfq
+
+import "slices"
+
+// GroupBy buckets the elements of q into per-key queues using the key
+// function, preserving the relative order of elements within each group.
+// The source queue is not modified.
+func GroupBy[T any, K comparable](q *Queue[T], key func(T) K) map[K]*Queue[T] {
+	groups := make(map[K]*Queue[T])
+	for i := 0; i < q.length; i++ {
+		v := *q.at(i)
+		k := key(v)
+		g, ok := groups[k]
+		if !ok {
+			g = NewQueue[T]()
+			groups[k] = g
+		}
+		g.PushBack(v)
+	}
+	return groups
+}
+
+// FlatMap applies fn to each element of q and concatenates the resulting
+// slices, in order, into a new queue. The source queue is not modified.
+func FlatMap[T, U any](q *Queue[T], fn func(T) []U) *Queue[U] {
+	out := NewQueue[U]()
+	for i := 0; i < q.length; i++ {
+		out.appendSlice(fn(*q.at(i)))
+	}
+	return out
+}
+
+// StableSortFunc sorts the elements of q in place by cmp, keeping equal
+// elements in their original order. cmp follows the slices.SortStableFunc
+// contract.
+func StableSortFunc[T any](q *Queue[T], cmp func(a, b T) int) {
+	slices.SortStableFunc(q.linearize(), cmp)
+	q.version++
+}
+
+// RotateTo rotates q so that the first occurrence of v becomes the front,
+// keeping the cyclic order of the other elements. It returns false and
+// leaves q unchanged if v is not present.
+func RotateTo[T comparable](q *Queue[T], v T) bool {
+	for i := 0; i < q.length; i++ {
+		if *q.at(i) == v {
+			q.rotate(i)
+			return true
+		}
+	}
+	return false
+}
+
+// Replace replaces every element of q equal to old with new, in place, and
+// returns the number of elements replaced.
+func Replace[T comparable](q *Queue[T], old, new T) int {
+	n := 0
+	for i := 0; i < q.length; i++ {
+		if p := q.at(i); *p == old {
+			*p = new
+			n++
+		}
+	}
+	if n > 0 {
+		q.version++
+	}
+	return n
+}
+
+// CloneFunc returns a copy of q in which every element has been passed
+// through copyFn, which can deep-copy pointers, slices or maps. The copy
+// has the same order, capacity and settings as q.
+func CloneFunc[T any](q *Queue[T], copyFn func(T) T) *Queue[T] {
+	c := q.cloneShape()
+	for i := range q.length {
+		c.buf[i] = copyFn(*q.at(i))
+	}
+	return c
+}
+
+// EqualSlice reports whether the elements of q equal those of s, element
+// for element and in order.
+func EqualSlice[T comparable](q *Queue[T], s []T) bool {
+	if q.length != len(s) {
+		return false
+	}
+	for i, v := range s {
+		if *q.at(i) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// HasDuplicates reports whether any value appears more than once in q.
+func HasDuplicates[T comparable](q *Queue[T]) bool {
+	seen := make(map[T]struct{}, q.length)
+	for i := 0; i < q.length; i++ {
+		v := *q.at(i)
+		if _, ok := seen[v]; ok {
+			return true
+		}
+		seen[v] = struct{}{}
+	}
+	return false
+}
+
+// FindDuplicates returns the values that appear more than once in q, each
+// listed once in the order of its second occurrence.
+func FindDuplicates[T comparable](q *Queue[T]) []T {
+	counts := make(map[T]int, q.length)
+	var dups []T
+	for i := 0; i < q.length; i++ {
+		v := *q.at(i)
+		counts[v]++
+		if counts[v] == 2 {
+			dups = append(dups, v)
+		}
+	}
+	return dups
+}
+
+// Scan returns a new queue holding the running accumulation of q: element i
+// is the accumulator after applying fn to init and the first i+1 elements.
+// It is the cumulative counterpart of a fold; q is not modified.
+func Scan[T, A any](q *Queue[T], init A, fn func(A, T) A) *Queue[A] {
+	out := newSized[A](q.length, nextPowerOfTwo(q.length))
+	acc := init
+	for i := range q.length {
+		acc = fn(acc, *q.at(i))
+		out.buf[i] = acc
+	}
+	return out
+}
+
+// Number is a constraint permitting any integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// AddToAll adds delta to every element of q in place, for example to rebase
+// a buffer of timestamps.
+func AddToAll[T Number](q *Queue[T], delta T) {
+	for i := 0; i < q.length; i++ {
+		*q.at(i) += delta
+	}
+	q.version++
+}
+
+// SplitAtValue splits q into the sub-queues separated by occurrences of
+// delim, dropping the delimiters, like strings.Split. Empty sub-queues
+// between consecutive delimiters or at either end are kept, so n delimiters
+// always yield n+1 queues. q is not modified.
+func SplitAtValue[T comparable](q *Queue[T], delim T) []*Queue[T] {
+	var parts []*Queue[T]
+	start := 0
+	for i := 0; i < q.length; i++ {
+		if *q.at(i) == delim {
+			parts = append(parts, q.span(start, i))
+			start = i + 1
+		}
+	}
+	return append(parts, q.span(start, q.length))
+}
+
+// trimmed returns the logical range [lo, hi) of q left after dropping
+// leading and trailing elements equal to zero.
+func trimmed[T comparable](q *Queue[T], zero T) (lo, hi int) {
+	lo, hi = 0, q.length
+	for lo < hi && *q.at(lo) == zero {
+		lo++
+	}
+	for hi > lo && *q.at(hi - 1) == zero {
+		hi--
+	}
+	return lo, hi
+}
+
+// EqualTrimmed reports whether a and b hold the same elements once leading
+// and trailing elements equal to zero are ignored, for comparing buffers
+// that differ only in padding.
+func EqualTrimmed[T comparable](a, b *Queue[T], zero T) bool {
+	alo, ahi := trimmed(a, zero)
+	blo, bhi := trimmed(b, zero)
+	if ahi-alo != bhi-blo {
+		return false
+	}
+	for i := 0; i < ahi-alo; i++ {
+		if *a.at(alo + i) != *b.at(blo + i) {
+			return false
+		}
+	}
+	return true
+}
+
+// RunLen is a run of Count consecutive elements equal to Value.
+type RunLen[T any] struct {
+	Value T
+	Count int
+}
+
+// RunLengthEncode returns a new queue describing q as runs of consecutive
+// equal elements, in order. q is not modified.
+func RunLengthEncode[T comparable](q *Queue[T]) *Queue[RunLen[T]] {
+	runs := NewQueue[RunLen[T]]()
+	for i := 0; i < q.length; i++ {
+		v := *q.at(i)
+		if last := runs.length - 1; last >= 0 && runs.at(last).Value == v {
+			runs.at(last).Count++
+			continue
+		}
+		runs.PushBack(RunLen[T]{Value: v, Count: 1})
+	}
+	return runs
+}
+
+// IsSorted reports whether the elements of q are in non-decreasing order
+// according to less. Empty and single-element queues are sorted.
+func IsSorted[T any](q *Queue[T], less func(a, b T) bool) bool {
+	for i := 1; i < q.length; i++ {
+		if less(*q.at(i), *q.at(i - 1)) {
+			return false
+		}
+	}
+	return true
+}
+
+// RotateToMin rotates q so that its minimum element according to less
+// becomes the front, keeping the cyclic order. When the minimum occurs more
+// than once, the occurrence that starts a run of minima is chosen, so a
+// rotated sorted queue is restored to sorted order.
+func RotateToMin[T any](q *Queue[T], less func(a, b T) bool) {
+	n := q.length
+	if n < 2 {
+		return
+	}
+	m := 0
+	for i := 1; i < n; i++ {
+		if less(*q.at(i), *q.at(m)) {
+			m = i
+		}
+	}
+	lowest := *q.at(m)
+	for i := 0; i < n; i++ {
+		v, prev := *q.at(i), *q.at((i + n - 1) % n)
+		if !less(lowest, v) && less(lowest, prev) {
+			q.rotate(i)
+			return
+		}
+	}
+}
+
+// Deinterleave splits q into the elements at even logical indices and those
+// at odd logical indices, each keeping its relative order, for example to
+// demultiplex a buffer of interleaved samples. q is not modified.
+func Deinterleave[T any](q *Queue[T]) (evens, odds *Queue[T]) {
+	ne, no := (q.length+1)/2, q.length/2
+	evens = newSized[T](ne, nextPowerOfTwo(ne))
+	odds = newSized[T](no, nextPowerOfTwo(no))
+	for i := range q.length {
+		if i%2 == 0 {
+			evens.buf[i/2] = *q.at(i)
+		} else {
+			odds.buf[i/2] = *q.at(i)
+		}
+	}
+	return evens, odds
+}
+
+// Interleave returns a new queue alternating the elements of a and b,
+// starting with a, followed by the remainder of the longer queue. It is the
+// inverse of Deinterleave. Neither input is modified.
+func Interleave[T any](a, b *Queue[T]) *Queue[T] {
+	n := a.length + b.length
+	out := newSized[T](n, nextPowerOfTwo(n))
+	shared := min(a.length, b.length)
+	for i := range shared {
+		out.buf[2*i] = *a.at(i)
+		out.buf[2*i+1] = *b.at(i)
+	}
+	if a.length > shared {
+		a.copyRange(out.buf[2*shared:], shared, a.length)
+	} else {
+		b.copyRange(out.buf[2*shared:], shared, b.length)
+	}
+	return out
+}
+
+// MinIndex returns the logical index and value of the smallest element of q
+// according to less, so it can then be modified or removed by index. Ties
+// resolve to the first occurrence. It returns -1 and false if q is empty.
+func MinIndex[T any](q *Queue[T], less func(a, b T) bool) (index int, value T, ok bool) {
+	if q.length == 0 {
+		return -1, value, false
+	}
+	for i := 1; i < q.length; i++ {
+		if less(*q.at(i), *q.at(index)) {
+			index = i
+		}
+	}
+	return index, *q.at(index), true
+}
+
+// MaxIndex returns the logical index and value of the largest element of q
+// according to less. Ties resolve to the first occurrence. It returns -1 and
+// false if q is empty.
+func MaxIndex[T any](q *Queue[T], less func(a, b T) bool) (index int, value T, ok bool) {
+	if q.length == 0 {
+		return -1, value, false
+	}
+	for i := 1; i < q.length; i++ {
+		if less(*q.at(index), *q.at(i)) {
+			index = i
+		}
+	}
+	return index, *q.at(index), true
+}
+
+// CountDistinct returns the number of distinct values in q, for example the
+// number of unique IDs in a buffer. q is not modified.
+func CountDistinct[T comparable](q *Queue[T]) int {
+	seen := make(map[T]struct{}, q.length)
+	for i := 0; i < q.length; i++ {
+		seen[*q.at(i)] = struct{}{}
+	}
+	return len(seen)
+}
+
+// Frequencies returns a map from each distinct value in q to the number of
+// times it occurs, a building block for histograms over buffered data. q is
+// not modified.
+func Frequencies[T comparable](q *Queue[T]) map[T]int {
+	counts := make(map[T]int)
+	for i := 0; i < q.length; i++ {
+		counts[*q.at(i)]++
+	}
+	return counts
+}
@@ -0,0 +1,598 @@
+package bfq
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushBack(i)
+	}
+
+	groups := GroupBy(q, func(v int) int { return v % 3 })
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+
+	// Each group keeps the source order
+	for k, g := range groups {
+		for want := k; want < 10; want += 3 {
+			if val, ok := g.PopFront(); !ok || val != want {
+				t.Errorf("Expected value %d in group %d, got %v", want, k, val)
+			}
+		}
+		if !g.IsEmpty() {
+			t.Errorf("Expected group %d to be drained, but it's not", k)
+		}
+	}
+
+	// The source queue is left untouched
+	if q.Len() != 10 {
+		t.Errorf("Expected source length 10, got %d", q.Len())
+	}
+}
+
+func TestGroupByEmpty(t *testing.T) {
+	groups := GroupBy(NewQueue[string](), func(s string) int { return len(s) })
+	if len(groups) != 0 {
+		t.Errorf("Expected no groups, got %d", len(groups))
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 3; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	// Each element n expands to n copies of itself
+	out := FlatMap(q, func(n int) []string {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = string(rune('a' + n))
+		}
+		return items
+	})
+	if s := out.String(); s != "[b c c d d d]" {
+		t.Errorf("Expected [b c c d d d], got %s", s)
+	}
+	if q.Len() != 4 {
+		t.Errorf("Expected source length 4, got %d", q.Len())
+	}
+}
+
+func TestFlatMapLarge(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 100; i++ {
+		q.PushBack(i)
+	}
+	out := FlatMap(q, func(n int) []int { return []int{n, n} })
+	if out.Len() != 200 {
+		t.Fatalf("Expected length 200, got %d", out.Len())
+	}
+	for i := 0; i < 200; i++ {
+		if val, ok := out.PopFront(); !ok || val != i/2 {
+			t.Errorf("Expected popped value %d, got %v", i/2, val)
+		}
+	}
+}
+
+func TestStableSortFunc(t *testing.T) {
+	// Push to the front so the contents wrap around the buffer
+	q := NewQueue[Data]()
+	names := []string{"a", "b", "c", "d", "e", "f", "g"}
+	ids := []int{3, 1, 3, 2, 1, 3, 2}
+	for i := len(ids) - 1; i >= 0; i-- {
+		q.PushFront(Data{ID: ids[i], Name: names[i]})
+	}
+
+	StableSortFunc(q, func(a, b Data) int { return a.ID - b.ID })
+
+	// Equal IDs keep their relative order
+	want := []string{"b", "e", "d", "g", "a", "c", "f"}
+	for _, name := range want {
+		if val, ok := q.PopFront(); !ok || val.Name != name {
+			t.Errorf("Expected element %s, got %v", name, val.Name)
+		}
+	}
+}
+
+func TestStableSortFuncValid(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 100; i++ {
+		q.PushFront(i)
+		q.PushBack(-i)
+	}
+	StableSortFunc(q, func(a, b int) int { return a - b })
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+	prev, _ := q.PopFront()
+	for !q.IsEmpty() {
+		v, _ := q.PopFront()
+		if v < prev {
+			t.Fatalf("Expected sorted order, got %d after %d", v, prev)
+		}
+		prev = v
+	}
+}
+
+func TestRotateTo(t *testing.T) {
+	tests := []struct {
+		v    int
+		want string
+	}{
+		{0, "[0 1 2 3 4 5 6 7 8 9]"},
+		{2, "[2 3 4 5 6 7 8 9 0 1]"},
+		{8, "[8 9 0 1 2 3 4 5 6 7]"},
+		{9, "[9 0 1 2 3 4 5 6 7 8]"},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := 9; i >= 0; i-- {
+			q.PushFront(i)
+		}
+		if !RotateTo(q, tt.v) {
+			t.Errorf("Expected RotateTo(%d) to succeed", tt.v)
+		}
+		if s := q.String(); s != tt.want {
+			t.Errorf("RotateTo(%d): expected %s, got %s", tt.v, tt.want, s)
+		}
+		if err := q.Validate(); err != nil {
+			t.Errorf("Unexpected invalid state: %v", err)
+		}
+	}
+}
+
+func TestRotateToFullBuffer(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 8; i++ {
+		q.PushBack(i)
+	}
+	RotateTo(q, 5)
+	if s := q.String(); s != "[5 6 7 0 1 2 3 4]" {
+		t.Errorf("Expected [5 6 7 0 1 2 3 4], got %s", s)
+	}
+	q.PushBack(8)
+	if back, ok := q.Back(); !ok || back != 8 {
+		t.Errorf("Expected back element 8, got %v", back)
+	}
+}
+
+func TestRotateToMissing(t *testing.T) {
+	q := NewQueue[int]()
+	q.PushBack(1)
+	q.PushBack(2)
+	if RotateTo(q, 3) {
+		t.Errorf("Expected RotateTo to fail for a missing value")
+	}
+	if s := q.String(); s != "[1 2]" {
+		t.Errorf("Expected queue to be unchanged, got %s", s)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	q := NewQueue[string]()
+	for _, s := range []string{"c", "x", "b", "x"} {
+		q.PushFront(s)
+	}
+	q.PushBack("x")
+
+	if n := Replace(q, "x", "y"); n != 3 {
+		t.Errorf("Expected 3 replacements, got %d", n)
+	}
+	if s := q.String(); s != "[y b y c y]" {
+		t.Errorf("Expected [y b y c y], got %s", s)
+	}
+	if n := Replace(q, "x", "z"); n != 0 {
+		t.Errorf("Expected 0 replacements, got %d", n)
+	}
+}
+
+func TestCloneFuncKeepsSettings(t *testing.T) {
+	q := Of(1, 2, 3)
+	q.SetGrowthFactor(1.5)
+	q.SetShrinkRatio(0.125)
+	q.SetFailFast(false)
+	for name, c := range map[string]*Queue[int]{
+		"CloneFunc": CloneFunc(q, func(v int) int { return v }),
+		"clone":     q.clone(),
+	} {
+		if c.growth != 1.5 || c.ratio != 0.125 || !c.lenient {
+			t.Errorf("%s: expected settings (1.5, 0.125, lenient), got (%v, %v, %v)", name, c.growth, c.ratio, c.lenient)
+		}
+		if !EqualSlice(c, []int{1, 2, 3}) || len(c.buf) != len(q.buf) {
+			t.Errorf("%s: expected [1 2 3] with capacity %d, got %v with %d", name, len(q.buf), c, len(c.buf))
+		}
+	}
+}
+
+func TestCloneFunc(t *testing.T) {
+	q := NewQueue[[]int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront([]int{i})
+	}
+
+	c := CloneFunc(q, func(s []int) []int { return append([]int(nil), s...) })
+	if c.Len() != q.Len() || len(c.buf) != len(q.buf) {
+		t.Errorf("Expected matching length and capacity, got %d/%d and %d/%d", c.Len(), len(c.buf), q.Len(), len(q.buf))
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+
+	// Mutating the clone's elements must not reach the source
+	c.Apply(func(s *[]int) { (*s)[0] = -1 })
+	for i := 9; i >= 0; i-- {
+		if val, _ := q.PopFront(); val[0] != i {
+			t.Errorf("Expected source element %d, got %v", i, val[0])
+		}
+	}
+}
+
+func TestEqualSlice(t *testing.T) {
+	q := NewQueue[int]()
+	if !EqualSlice(q, nil) || !EqualSlice(q, []int{}) {
+		t.Errorf("Expected empty queue to equal an empty slice")
+	}
+
+	for i := 3; i >= 1; i-- {
+		q.PushFront(i)
+	}
+	tests := []struct {
+		s    []int
+		want bool
+	}{
+		{[]int{1, 2, 3}, true},
+		{[]int{1, 2}, false},
+		{[]int{1, 2, 3, 4}, false},
+		{[]int{1, 2, 4}, false},
+		{[]int{3, 2, 1}, false},
+	}
+	for _, tt := range tests {
+		if got := EqualSlice(q, tt.s); got != tt.want {
+			t.Errorf("EqualSlice(%v): expected %v, got %v", tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestHasDuplicatesAndFindDuplicates(t *testing.T) {
+	q := NewQueue[int]()
+	if HasDuplicates(q) || len(FindDuplicates(q)) != 0 {
+		t.Errorf("Expected no duplicates in empty queue")
+	}
+
+	for _, v := range []int{1, 2, 3, 4} {
+		q.PushBack(v)
+	}
+	if HasDuplicates(q) || len(FindDuplicates(q)) != 0 {
+		t.Errorf("Expected no duplicates in %s", q)
+	}
+
+	for _, v := range []int{3, 1, 3, 5} {
+		q.PushFront(v)
+	}
+	// [5 3 1 3 1 2 3 4]
+	if !HasDuplicates(q) {
+		t.Errorf("Expected duplicates in %s", q)
+	}
+	if dups := FindDuplicates(q); !slices.Equal(dups, []int{3, 1}) {
+		t.Errorf("Expected duplicates [3 1], got %v", dups)
+	}
+}
+
+func TestScan(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 8; i >= 1; i-- {
+		q.PushFront(i)
+	}
+
+	sums := Scan(q, 0, func(acc, v int) int { return acc + v })
+	if !EqualSlice(sums, []int{1, 3, 6, 10, 15, 21, 28, 36}) {
+		t.Errorf("Expected running sums, got %s", sums)
+	}
+	if err := sums.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+
+	// The accumulator type can differ from the element type
+	labels := Scan(q, "", func(acc string, v int) string { return acc + string(rune('0'+v)) })
+	if back, _ := labels.Back(); back != "12345678" {
+		t.Errorf("Expected final accumulator \"12345678\", got %q", back)
+	}
+
+	if Scan(NewQueue[int](), 0, func(acc, v int) int { return acc + v }).Len() != 0 {
+		t.Errorf("Expected empty scan of empty queue")
+	}
+}
+
+func TestAddToAll(t *testing.T) {
+	q := NewQueue[int64]()
+	for i := int64(0); i < 10; i++ {
+		q.PushFront(1000 + i)
+	}
+
+	AddToAll(q, -1000)
+	if !EqualSlice(q, []int64{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}) {
+		t.Errorf("Expected rebased values, got %s", q)
+	}
+
+	type celsius float64
+	temps := Of[celsius](1.5, -2)
+	AddToAll(temps, 0.5)
+	if !EqualSlice(temps, []celsius{2, -1.5}) {
+		t.Errorf("Expected [2 -1.5], got %s", temps)
+	}
+}
+
+func TestSplitAtValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"a,b,c", []string{"a", "b", "c"}},
+		{"a,,b", []string{"a", "", "b"}},
+		{",a,", []string{"", "a", ""}},
+		{"abc", []string{"abc"}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		q := NewQueue[rune]()
+		for _, r := range tt.in {
+			q.PushBack(r)
+		}
+		parts := SplitAtValue(q, ',')
+		if len(parts) != len(tt.want) {
+			t.Errorf("SplitAtValue(%q): expected %d parts, got %d", tt.in, len(tt.want), len(parts))
+			continue
+		}
+		for i, part := range parts {
+			if !EqualSlice(part, []rune(tt.want[i])) {
+				t.Errorf("SplitAtValue(%q): expected part %q, got %s", tt.in, tt.want[i], part)
+			}
+		}
+		if q.Len() != len([]rune(tt.in)) {
+			t.Errorf("Expected source to be unmodified")
+		}
+	}
+}
+
+func TestEqualTrimmed(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want bool
+	}{
+		{[]int{0, 0, 1, 2, 0}, []int{1, 2, 0, 0, 0}, true},
+		{[]int{1, 0, 2}, []int{0, 1, 0, 2, 0}, true},
+		{[]int{1, 2}, []int{1, 0, 2}, false},
+		{[]int{1, 2}, []int{2, 1}, false},
+		{[]int{0, 0}, nil, true},
+		{[]int{0, 5}, []int{5, 5}, false},
+	}
+	for _, tt := range tests {
+		a, b := NewQueue[int](), NewQueue[int]()
+		for i := len(tt.a) - 1; i >= 0; i-- {
+			a.PushFront(tt.a[i])
+		}
+		for _, v := range tt.b {
+			b.PushBack(v)
+		}
+		if got := EqualTrimmed(a, b, 0); got != tt.want {
+			t.Errorf("EqualTrimmed(%v, %v): expected %v, got %v", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestRunLengthEncode(t *testing.T) {
+	q := NewQueue[byte]()
+	for _, b := range []byte("aaabccddddde") {
+		q.PushBack(b)
+	}
+
+	runs := RunLengthEncode(q)
+	want := []RunLen[byte]{{'a', 3}, {'b', 1}, {'c', 2}, {'d', 5}, {'e', 1}}
+	if !EqualSlice(runs, want) {
+		t.Errorf("Expected %v, got %s", want, runs)
+	}
+	if q.Len() != 12 {
+		t.Errorf("Expected source to be unmodified")
+	}
+
+	if RunLengthEncode(NewQueue[int]()).Len() != 0 {
+		t.Errorf("Expected no runs for an empty queue")
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	tests := []struct {
+		items []int
+		want  bool
+	}{
+		{nil, true},
+		{[]int{1}, true},
+		{[]int{1, 1, 2, 3}, true},
+		{[]int{1, 3, 2}, false},
+		{[]int{3, 2, 1}, false},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := len(tt.items) - 1; i >= 0; i-- {
+			q.PushFront(tt.items[i])
+		}
+		if got := IsSorted(q, less); got != tt.want {
+			t.Errorf("IsSorted(%v): expected %v, got %v", tt.items, tt.want, got)
+		}
+	}
+
+	// Across the wraparound
+	q := Tabulate(20, func(i int) int { return i })
+	for i := 0; i < 5; i++ {
+		v, _ := q.PopFront()
+		q.PushBack(v + 20)
+	}
+	if !IsSorted(q, less) {
+		t.Errorf("Expected wrapped queue %s to be sorted", q)
+	}
+}
+
+func TestRotateToMin(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	tests := []struct {
+		items, want []int
+	}{
+		{nil, nil},
+		{[]int{4}, []int{4}},
+		{[]int{4, 5, 6, 1, 2, 3}, []int{1, 2, 3, 4, 5, 6}},
+		{[]int{1, 2, 3}, []int{1, 2, 3}},
+		{[]int{1, 2, 1, 1}, []int{1, 1, 1, 2}},
+		{[]int{3, 3, 3}, []int{3, 3, 3}},
+		{[]int{5, 9, 0, 7}, []int{0, 7, 5, 9}},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := len(tt.items) - 1; i >= 0; i-- {
+			q.PushFront(tt.items[i])
+		}
+		RotateToMin(q, less)
+		if !EqualSlice(q, tt.want) {
+			t.Errorf("RotateToMin(%v): expected %v, got %s", tt.items, tt.want, q)
+		}
+	}
+}
+
+func TestDeinterleave(t *testing.T) {
+	// [0 1 ... 18] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < 10; i++ {
+		q.PopFront()
+		q.PushBack(10 + i)
+	}
+	q.PushBack(20)
+
+	evens, odds := Deinterleave(q)
+	if err := evens.Validate(); err != nil {
+		t.Fatalf("evens is invalid: %v", err)
+	}
+	if err := odds.Validate(); err != nil {
+		t.Fatalf("odds is invalid: %v", err)
+	}
+	if !EqualSlice(evens, []int{10, 12, 14, 16, 18, 20}) {
+		t.Errorf("Expected evens [10 12 14 16 18 20], got %v", evens)
+	}
+	if !EqualSlice(odds, []int{11, 13, 15, 17, 19}) {
+		t.Errorf("Expected odds [11 13 15 17 19], got %v", odds)
+	}
+	if q.Len() != 11 {
+		t.Errorf("Expected the source to keep 11 elements, got %d", q.Len())
+	}
+
+	evens, odds = Deinterleave(NewQueue[int]())
+	if !evens.IsEmpty() || !odds.IsEmpty() {
+		t.Errorf("Expected two empty queues, got %v and %v", evens, odds)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	a, b := Of(1, 3, 5, 7, 9), Of(2, 4)
+	if got := Interleave(a, b); !EqualSlice(got, []int{1, 2, 3, 4, 5, 7, 9}) {
+		t.Errorf("Expected [1 2 3 4 5 7 9], got %v", got)
+	}
+	if got := Interleave(b, a); !EqualSlice(got, []int{2, 1, 4, 3, 5, 7, 9}) {
+		t.Errorf("Expected [2 1 4 3 5 7 9], got %v", got)
+	}
+	if !EqualSlice(a, []int{1, 3, 5, 7, 9}) || !EqualSlice(b, []int{2, 4}) {
+		t.Errorf("Expected inputs to be unmodified, got %v and %v", a, b)
+	}
+
+	// Round trip through Deinterleave
+	q := NewQueue[int]()
+	want := make([]int, 13)
+	for i := 0; i < 13; i++ {
+		q.PushFront(i)
+		want[12-i] = i
+	}
+	got := Interleave(Deinterleave(q))
+	if err := got.Validate(); err != nil {
+		t.Fatalf("Interleave result is invalid: %v", err)
+	}
+	if !EqualSlice(got, want) {
+		t.Errorf("Expected %v after a round trip, got %v", want, got)
+	}
+
+	if got := Interleave(NewQueue[int](), NewQueue[int]()); !got.IsEmpty() {
+		t.Errorf("Expected an empty queue, got %v", got)
+	}
+}
+
+func TestMinMaxIndex(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	q := Of(4, 1, 7, 1, 7, 3)
+	if i, v, ok := MinIndex(q, less); !ok || i != 1 || v != 1 {
+		t.Errorf("Expected MinIndex (1, 1, true), got (%d, %d, %v)", i, v, ok)
+	}
+	if i, v, ok := MaxIndex(q, less); !ok || i != 2 || v != 7 {
+		t.Errorf("Expected MaxIndex (2, 7, true), got (%d, %d, %v)", i, v, ok)
+	}
+
+	// Indices are logical even when the buffer wraps
+	w := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		w.PushFront(i)
+	}
+	if i, v, _ := MinIndex(w, less); i != 9 || v != 0 {
+		t.Errorf("Expected MinIndex (9, 0), got (%d, %d)", i, v)
+	}
+	if i, v, _ := MaxIndex(w, less); i != 0 || v != 9 {
+		t.Errorf("Expected MaxIndex (0, 9), got (%d, %d)", i, v)
+	}
+
+	empty := NewQueue[int]()
+	if i, _, ok := MinIndex(empty, less); ok || i != -1 {
+		t.Errorf("Expected MinIndex on empty queue to return (-1, false), got (%d, %v)", i, ok)
+	}
+	if i, _, ok := MaxIndex(empty, less); ok || i != -1 {
+		t.Errorf("Expected MaxIndex on empty queue to return (-1, false), got (%d, %v)", i, ok)
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	// [0 2 1 0 2 ...] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i % 3)
+	}
+	if n := CountDistinct(q); n != 3 {
+		t.Errorf("Expected 3 distinct values, got %d", n)
+	}
+	if n := CountDistinct(Of("a", "b", "c", "d")); n != 4 {
+		t.Errorf("Expected 4 distinct values, got %d", n)
+	}
+	if n := CountDistinct(NewQueue[int]()); n != 0 {
+		t.Errorf("Expected 0 for an empty queue, got %d", n)
+	}
+}
+
+func TestFrequencies(t *testing.T) {
+	// [0 2 1 0 2 ...] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i % 3)
+	}
+	got := Frequencies(q)
+	if want := map[int]int{0: 4, 1: 3, 2: 3}; !maps.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if len(got) != CountDistinct(q) {
+		t.Errorf("Expected %d entries to match CountDistinct, got %d", CountDistinct(q), len(got))
+	}
+	if got := Frequencies(NewQueue[string]()); len(got) != 0 {
+		t.Errorf("Expected an empty map, got %v", got)
+	}
+}
@@ -0,0 +1,66 @@
+package bfq
+
+// Heapify rearranges the elements of q in place into binary-heap order with
+// respect to less, so that the front is the smallest element. Logical index
+// i has children at 2i+1 and 2i+2, as seen through At-style indexing.
+func Heapify[T any](q *Queue[T], less func(a, b T) bool) {
+	for i := q.length/2 - 1; i >= 0; i-- {
+		siftDown(q, i, less)
+	}
+	q.version++
+}
+
+// HeapPush pushes v onto a heap-ordered queue, preserving the heap property.
+func HeapPush[T any](q *Queue[T], v T, less func(a, b T) bool) {
+	q.PushBack(v)
+	siftUp(q, q.length-1, less)
+}
+
+// HeapPop removes and returns the smallest element of a heap-ordered queue,
+// preserving the heap property. It returns false if the queue is empty.
+func HeapPop[T any](q *Queue[T], less func(a, b T) bool) (T, bool) {
+	if q.IsEmpty() {
+		var zero T
+		return zero, false
+	}
+	top, last := q.at(0), q.at(q.length-1)
+	*top, *last = *last, *top
+	v, _ := q.PopBack()
+	siftDown(q, 0, less)
+	return v, true
+}
+
+// siftUp restores the heap property by moving the element at logical
+// index i toward the front.
+func siftUp[T any](q *Queue[T], i int, less func(a, b T) bool) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		child, p := q.at(i), q.at(parent)
+		if !less(*child, *p) {
+			return
+		}
+		*child, *p = *p, *child
+		i = parent
+	}
+}
+
+// siftDown restores the heap property by moving the element at logical
+// index i toward the back.
+func siftDown[T any](q *Queue[T], i int, less func(a, b T) bool) {
+	n := q.length
+	for {
+		smallest := i
+		if l := 2*i + 1; l < n && less(*q.at(l), *q.at(smallest)) {
+			smallest = l
+		}
+		if r := 2*i + 2; r < n && less(*q.at(r), *q.at(smallest)) {
+			smallest = r
+		}
+		if smallest == i {
+			return
+		}
+		a, b := q.at(i), q.at(smallest)
+		*a, *b = *b, *a
+		i = smallest
+	}
+}
@@ -0,0 +1,61 @@
+package bfq
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkHeap reports the first logical index violating the heap property, or -1.
+func checkHeap[T any](q *Queue[T], less func(a, b T) bool) int {
+	for i := 1; i < q.Len(); i++ {
+		if less(*q.at(i), *q.at((i - 1) / 2)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestHeapify(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 7, 100, 1000} {
+		q := NewQueue[int]()
+		for i := 0; i < n; i++ {
+			q.PushFront(r.Intn(100))
+		}
+		Heapify(q, less)
+		if i := checkHeap(q, less); i >= 0 {
+			t.Errorf("Heap property violated at index %d for n=%d", i, n)
+		}
+		if q.Len() != n {
+			t.Errorf("Expected queue length %d, got %d", n, q.Len())
+		}
+	}
+}
+
+func TestHeapPushAndHeapPop(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	q := NewQueue[int]()
+	for _, v := range []int{5, 3, 8, 1, 9, 2} {
+		q.PushBack(v)
+	}
+	Heapify(q, less)
+
+	HeapPush(q, 0, less)
+	HeapPush(q, 7, less)
+	if i := checkHeap(q, less); i >= 0 {
+		t.Errorf("Heap property violated at index %d after push", i)
+	}
+
+	for _, want := range []int{0, 1, 2, 3, 5, 7, 8, 9} {
+		if val, ok := HeapPop(q, less); !ok || val != want {
+			t.Errorf("Expected popped value %d, got %v", want, val)
+		}
+		if i := checkHeap(q, less); i >= 0 {
+			t.Errorf("Heap property violated at index %d after pop", i)
+		}
+	}
+	if _, ok := HeapPop(q, less); ok {
+		t.Errorf("Expected HeapPop to return false on empty queue")
+	}
+}
@@ -0,0 +1,201 @@
+package bfq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// minRead is the smallest free space ReadFrom reserves before each read.
+const minRead = 512
+
+// writeChunk is the amount of formatted output WriteJoined collects before
+// passing it to the writer.
+const writeChunk = 4096
+
+// ByteQueue is a Queue of bytes that also integrates with the io package,
+// making it usable as a growable read buffer. All Queue methods are
+// available on it.
+type ByteQueue struct {
+	Queue[byte]
+}
+
+// NewByteQueue creates an empty byte queue.
+func NewByteQueue() *ByteQueue {
+	return &ByteQueue{Queue: Queue[byte]{buf: make([]byte, minCapacity)}}
+}
+
+// ReadFrom reads from r until EOF, appending the data to the back. Reads go
+// straight into the free space of the buffer, which grows as needed. It
+// returns the number of bytes read; io.EOF is not reported as an error.
+func (q *ByteQueue) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		if len(q.buf)-q.length < minRead {
+			q.reserve(minRead)
+		}
+		if q.length == 0 {
+			q.front, q.back = 0, 0
+		}
+		// The free space starting at back is contiguous up to either the
+		// end of the buffer or the front, whichever comes first.
+		end := len(q.buf)
+		if q.back < q.front {
+			end = q.front
+		}
+		n, err := r.Read(q.buf[q.back:end])
+		if n < 0 || n > end-q.back {
+			panic("bfq: reader returned invalid count")
+		}
+		q.back = q.wrap(q.back + n)
+		q.length += n
+		q.pushed = sideBack
+		q.version++
+		q.notifyFirst(q.length - n)
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo drains the queue from the front into w until it is empty or a
+// write fails. Bytes accepted by w are removed even if an error occurs.
+// It returns the number of bytes written.
+func (q *ByteQueue) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	defer q.compact()
+	for q.length > 0 {
+		end := min(q.front+q.length, len(q.buf))
+		chunk := q.buf[q.front:end]
+		n, err := w.Write(chunk)
+		if n < 0 || n > len(chunk) {
+			panic("bfq: writer returned invalid count")
+		}
+		q.front = q.wrap(q.front + n)
+		q.length -= n
+		q.version++
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if n < len(chunk) {
+			return total, io.ErrShortWrite
+		}
+	}
+	return total, nil
+}
+
+// Write appends p to the back of the queue. It always returns len(p), nil.
+func (q *ByteQueue) Write(p []byte) (int, error) {
+	q.appendSlice(p)
+	return len(p), nil
+}
+
+// Read drains up to len(p) bytes from the front of the queue into p. It
+// returns io.EOF when the queue is empty and p is not.
+func (q *ByteQueue) Read(p []byte) (int, error) {
+	if q.length == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := q.copyTo(p)
+	q.front = q.wrap(q.front + n)
+	q.length -= n
+	q.version++
+	q.compact()
+	return n, nil
+}
+
+// LinesFromReader reads r to the end and returns a queue holding its lines
+// in order, without their line endings. Lines longer than
+// bufio.MaxScanTokenSize cause bufio.ErrTooLong; use LinesFromReaderSize to
+// allow longer lines. On error the lines read so far are returned with it.
+func LinesFromReader(r io.Reader) (*Queue[string], error) {
+	return LinesFromReaderSize(r, bufio.MaxScanTokenSize)
+}
+
+// LinesFromReaderSize is like LinesFromReader but accepts lines of up to
+// maxLine bytes. A maxLine of 0 or less means bufio.MaxScanTokenSize.
+func LinesFromReaderSize(r io.Reader, maxLine int) (*Queue[string], error) {
+	if maxLine <= 0 {
+		maxLine = bufio.MaxScanTokenSize
+	}
+	q := NewQueue[string]()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, min(maxLine, 4096)), maxLine)
+	for sc.Scan() {
+		q.PushBack(sc.Text())
+	}
+	return q, sc.Err()
+}
+
+// WriteJoined writes the elements to w front to back, formatted as with
+// fmt's %v and separated by sep, without building the whole output in
+// memory. It returns the number of bytes written and the first write error.
+// Strings, booleans, integers and float64 skip fmt entirely.
+//
+// It is not named WriteTo because that name is reserved for io.WriterTo.
+func (q *Queue[T]) WriteJoined(w io.Writer, sep string) (int64, error) {
+	var total int64
+	buf := make([]byte, 0, writeChunk+minRead)
+	flush := func() error {
+		n, err := w.Write(buf)
+		total += int64(n)
+		if err == nil && n < len(buf) {
+			err = io.ErrShortWrite
+		}
+		buf = buf[:0]
+		return err
+	}
+	for i := range q.length {
+		if i > 0 {
+			buf = append(buf, sep...)
+		}
+		buf = appendValue(buf, q.at(i))
+		if len(buf) >= writeChunk {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if len(buf) > 0 {
+		if err := flush(); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// appendValue appends *p formatted as with %v to buf. It switches on the
+// pointer rather than the value so common element types are formatted
+// without boxing them.
+func appendValue[T any](buf []byte, p *T) []byte {
+	switch x := any(p).(type) {
+	case *string:
+		return append(buf, *x...)
+	case *bool:
+		return strconv.AppendBool(buf, *x)
+	case *int:
+		return strconv.AppendInt(buf, int64(*x), 10)
+	case *int32:
+		return strconv.AppendInt(buf, int64(*x), 10)
+	case *int64:
+		return strconv.AppendInt(buf, *x, 10)
+	case *uint:
+		return strconv.AppendUint(buf, uint64(*x), 10)
+	case *uint32:
+		return strconv.AppendUint(buf, uint64(*x), 10)
+	case *uint64:
+		return strconv.AppendUint(buf, *x, 10)
+	case *float64:
+		return strconv.AppendFloat(buf, *x, 'g', -1, 64)
+	}
+	return fmt.Append(buf, *p)
+}
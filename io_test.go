@@ -0,0 +1,304 @@
+package bfq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestByteQueueReadFrom(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+	q := NewByteQueue()
+	q.PushBack('>')
+
+	// One byte at a time exercises every growth step
+	n, err := q.ReadFrom(iotest.OneByteReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("Expected %d bytes read, got %d", len(data), n)
+	}
+	if q.Len() != len(data)+1 {
+		t.Errorf("Expected queue length %d, got %d", len(data)+1, q.Len())
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+	if front, _ := q.PopFront(); front != '>' {
+		t.Errorf("Expected existing data to stay at the front, got %q", front)
+	}
+	for i := 0; i < len(data); i++ {
+		if b, _ := q.PopFront(); b != data[i] {
+			t.Fatalf("Expected byte %q at %d, got %q", data[i], i, b)
+		}
+	}
+}
+
+func TestByteQueueReadFromWrapped(t *testing.T) {
+	q := NewByteQueue()
+	for i := 0; i < 6; i++ {
+		q.PushBack('x')
+	}
+	for i := 0; i < 4; i++ {
+		q.PopFront()
+	}
+
+	if _, err := q.ReadFrom(strings.NewReader("abcdefghij")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	for !q.IsEmpty() {
+		b, _ := q.PopFront()
+		sb.WriteByte(b)
+	}
+	if sb.String() != "xxabcdefghij" {
+		t.Errorf("Expected xxabcdefghij, got %s", sb.String())
+	}
+}
+
+func TestByteQueueReadFromError(t *testing.T) {
+	boom := errors.New("boom")
+	q := NewByteQueue()
+	r := io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(boom))
+	n, err := q.ReadFrom(r)
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected reader error, got %v", err)
+	}
+	if n != 3 || q.Len() != 3 {
+		t.Errorf("Expected 3 bytes kept, got n=%d len=%d", n, q.Len())
+	}
+}
+
+func TestByteQueueWriteTo(t *testing.T) {
+	q := NewByteQueue()
+	for _, b := range []byte("world") {
+		q.PushBack(b)
+	}
+	for _, b := range []byte(" olleh") {
+		q.PushFront(b)
+	}
+
+	var buf bytes.Buffer
+	n, err := q.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 11 || buf.String() != "hello world" {
+		t.Errorf("Expected 11 bytes \"hello world\", got %d bytes %q", n, buf.String())
+	}
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be drained, but it's not")
+	}
+}
+
+func TestByteQueueWriteToShortWrite(t *testing.T) {
+	q := NewByteQueue()
+	q.ReadFrom(strings.NewReader("abcdef"))
+
+	n, err := q.WriteTo(iotest.TruncateWriter(io.Discard, 4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("Expected 6 bytes reported, got %d", n)
+	}
+
+	q.ReadFrom(strings.NewReader("abcdef"))
+	n, err = q.WriteTo(shortWriter{max: 4})
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Expected io.ErrShortWrite, got %v", err)
+	}
+	if n != 4 || q.Len() != 2 {
+		t.Errorf("Expected 4 bytes written and 2 left, got n=%d len=%d", n, q.Len())
+	}
+}
+
+// shortWriter accepts at most max bytes per call without reporting an error.
+type shortWriter struct{ max int }
+
+func (w shortWriter) Write(p []byte) (int, error) { return min(len(p), w.max), nil }
+
+func TestByteQueueWriteAndRead(t *testing.T) {
+	q := NewByteQueue()
+	var _ io.ReadWriter = q
+
+	// Interleave writes and partial reads so the data wraps around
+	var got []byte
+	p := make([]byte, 3)
+	for i := 0; i < 50; i++ {
+		if n, err := q.Write([]byte("abcde")); n != 5 || err != nil {
+			t.Fatalf("Unexpected write result %d, %v", n, err)
+		}
+		n, err := q.Read(p)
+		if n != 3 || err != nil {
+			t.Fatalf("Unexpected read result %d, %v", n, err)
+		}
+		got = append(got, p[:n]...)
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+
+	rest, err := io.ReadAll(q)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got = append(got, rest...)
+	if want := strings.Repeat("abcde", 50); string(got) != want {
+		t.Errorf("Expected the written data back in order, got %q", got)
+	}
+}
+
+func TestByteQueueReadEmpty(t *testing.T) {
+	q := NewByteQueue()
+	if n, err := q.Read(nil); n != 0 || err != nil {
+		t.Errorf("Expected 0, nil for empty read, got %d, %v", n, err)
+	}
+	if n, err := q.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("Expected 0, io.EOF, got %d, %v", n, err)
+	}
+}
+
+func TestByteQueueCopy(t *testing.T) {
+	data := strings.Repeat("pipe buffer ", 500)
+	q := NewByteQueue()
+	if _, err := io.Copy(q, strings.NewReader(data)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, q); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != data {
+		t.Errorf("Expected data to round-trip through the queue")
+	}
+}
+
+func TestLinesFromReader(t *testing.T) {
+	q, err := LinesFromReader(strings.NewReader("one\ntwo\r\n\nfour"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !EqualSlice(q, []string{"one", "two", "", "four"}) {
+		t.Errorf("Expected [one two  four], got %q", q.Steal())
+	}
+
+	// Long lines need a larger buffer
+	long := strings.Repeat("x", 100) + "\nshort\n"
+	q, err = LinesFromReaderSize(strings.NewReader(long), 64)
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Expected bufio.ErrTooLong, got %v", err)
+	}
+	if !q.IsEmpty() {
+		t.Errorf("Expected no lines before the error, got %d", q.Len())
+	}
+	q, err = LinesFromReaderSize(strings.NewReader(long), 128)
+	if err != nil || q.Len() != 2 {
+		t.Errorf("Expected 2 lines and no error, got %d and %v", q.Len(), err)
+	}
+
+	// A non-positive limit falls back to the default
+	for _, maxLine := range []int{0, -1} {
+		q, err = LinesFromReaderSize(strings.NewReader(long), maxLine)
+		if err != nil || q.Len() != 2 {
+			t.Errorf("maxLine %d: expected 2 lines and no error, got %d and %v", maxLine, q.Len(), err)
+		}
+	}
+
+	// Reader errors are reported along with the lines read so far
+	q, err = LinesFromReader(iotest.TimeoutReader(strings.NewReader("a\nb\n")))
+	if !errors.Is(err, iotest.ErrTimeout) {
+		t.Errorf("Expected iotest.ErrTimeout, got %v", err)
+	}
+	if !EqualSlice(q, []string{"a", "b"}) {
+		t.Errorf("Expected the lines read before the error, got %q", q.Steal())
+	}
+}
+
+func TestWriteJoined(t *testing.T) {
+	var b strings.Builder
+	n, err := Of(1, -2, 30).WriteJoined(&b, ", ")
+	if err != nil || n != 9 || b.String() != "1, -2, 30" {
+		t.Errorf("Expected (9, nil) and %q, got (%d, %v) and %q", "1, -2, 30", n, err, b.String())
+	}
+
+	// Fast paths format like fmt
+	for _, vals := range [][]any{
+		{"a b", true, int32(-7), int64(1 << 40), uint(3), uint32(4), uint64(1 << 63)},
+		{0.1, 1e6, 1e21, -2.5e-7, 3.0},
+		{Data{1, "x"}, []byte("hi"), nil, 'r'},
+	} {
+		b.Reset()
+		if _, err := Of(vals...).WriteJoined(&b, "|"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := strings.TrimSuffix(fmt.Sprintln(vals...), "\n")
+		if got := strings.ReplaceAll(b.String(), "|", " "); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+
+	// Output larger than one chunk is written in several calls
+	q := NewQueue[string]()
+	for i := 0; i < 3*writeChunk; i++ {
+		q.PushBack("ab")
+	}
+	var bb bytes.Buffer
+	n, err = q.WriteJoined(&bb, "-")
+	if want := strings.Repeat("ab-", 3*writeChunk-1) + "ab"; err != nil || n != int64(len(want)) || bb.String() != want {
+		t.Errorf("Expected %d bytes of repeated output, got %d (%v)", len(want), n, err)
+	}
+
+	// Write errors stop the output
+	n, err = q.WriteJoined(shortWriter{max: 10}, "-")
+	if !errors.Is(err, io.ErrShortWrite) || n != 10 {
+		t.Errorf("Expected (10, io.ErrShortWrite), got (%d, %v)", n, err)
+	}
+
+	b.Reset()
+	if n, err := NewQueue[int]().WriteJoined(&b, ","); n != 0 || err != nil || b.Len() != 0 {
+		t.Errorf("Expected nothing written for an empty queue, got (%d, %v) and %q", n, err, b.String())
+	}
+}
+
+func TestWriteJoinedAllocs(t *testing.T) {
+	ints := NewQueue[int]()
+	strs := NewQueue[string]()
+	for i := 0; i < 10000; i++ {
+		ints.PushBack(i)
+		strs.PushBack("abc")
+	}
+	// The fast path must not allocate per element
+	for name, write := range map[string]func(){
+		"int":    func() { ints.WriteJoined(io.Discard, ",") },
+		"string": func() { strs.WriteJoined(io.Discard, ",") },
+	} {
+		if allocs := testing.AllocsPerRun(10, write); allocs > 5 {
+			t.Errorf("Expected a constant number of allocations for %s elements, got %v", name, allocs)
+		}
+	}
+}
+
+func TestByteQueueOnFirstElement(t *testing.T) {
+	q := NewByteQueue()
+	calls := 0
+	q.OnFirstElement(func() { calls++ })
+	q.Write([]byte("ab"))
+	q.Write([]byte("cd"))
+	if calls != 1 {
+		t.Errorf("Expected 1 call from Write, got %d", calls)
+	}
+	io.Copy(io.Discard, q)
+	if _, err := q.ReadFrom(iotest.OneByteReader(strings.NewReader("xyz"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 1 more call from ReadFrom, got %d", calls-1)
+	}
+}
@@ -0,0 +1,82 @@
+package bfq
+
+// SetFailFast controls whether iterators panic when the queue is modified
+// while they are in use. It is enabled by default; callers that knowingly
+// mutate the queue during iteration can disable it, in which case the
+// iterators see the live contents and simply stop at the current end.
+func (q *Queue[T]) SetFailFast(enabled bool) { q.lenient = !enabled }
+
+// checkVersion panics if fail-fast iteration is enabled and the queue has
+// been modified since an iterator captured version v.
+func (q *Queue[T]) checkVersion(v uint64) {
+	if q.version != v && !q.lenient {
+		panic("bfq: queue modified during iteration")
+	}
+}
+
+// Iterator walks the elements of a queue from front to back.
+//
+// An Iterator reads the live buffer of its queue. Modifying the queue while
+// it is in use makes the next call to Next panic, unless fail-fast
+// iteration has been disabled with SetFailFast.
+type Iterator[T any] struct {
+	q       *Queue[T]
+	pos     int
+	version uint64
+}
+
+// Iterator returns an iterator positioned at the front of the queue.
+func (q *Queue[T]) Iterator() *Iterator[T] {
+	return &Iterator[T]{q: q, version: q.version}
+}
+
+// HasNext reports whether there are elements left to visit.
+func (it *Iterator[T]) HasNext() bool { return it.pos < it.q.length }
+
+// Next returns the next element and advances the iterator.
+// It returns the zero value once the iterator is exhausted.
+func (it *Iterator[T]) Next() T {
+	it.q.checkVersion(it.version)
+	if !it.HasNext() {
+		var zero T
+		return zero
+	}
+	v := *it.q.at(it.pos)
+	it.pos++
+	return v
+}
+
+// Cursor is a read-only position within a queue that can be advanced and
+// resumed later without removing elements.
+//
+// A Cursor tracks a logical index from the front. Pushing or popping at the
+// front shifts the element it refers to; a Cursor whose index falls outside
+// the queue simply reports no value.
+type Cursor[T any] struct {
+	q   *Queue[T]
+	pos int
+}
+
+// Cursor returns a cursor positioned at the front of the queue.
+func (q *Queue[T]) Cursor() *Cursor[T] {
+	return &Cursor[T]{q: q}
+}
+
+// Value returns the element under the cursor, or false if the cursor is
+// past the end of the queue.
+func (c *Cursor[T]) Value() (T, bool) {
+	if c.pos >= c.q.length {
+		var zero T
+		return zero, false
+	}
+	return *c.q.at(c.pos), true
+}
+
+// Advance moves the cursor to the next element and reports whether it now
+// refers to one.
+func (c *Cursor[T]) Advance() bool {
+	if c.pos < c.q.length {
+		c.pos++
+	}
+	return c.pos < c.q.length
+}
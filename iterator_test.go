@@ -0,0 +1,145 @@
+package bfq
+
+import "testing"
+
+func TestIterator(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 20; i++ {
+		q.PushFront(i)
+	}
+
+	// Walk front-to-back across the wraparound
+	want := 19
+	for it := q.Iterator(); it.HasNext(); {
+		if v := it.Next(); v != want {
+			t.Errorf("Expected value %d, got %v", want, v)
+		}
+		want--
+	}
+	if want != -1 {
+		t.Errorf("Expected to visit 20 elements, visited %d", 19-want)
+	}
+
+	// Iteration must not consume the queue
+	if q.Len() != 20 {
+		t.Errorf("Expected queue length 20, got %d", q.Len())
+	}
+}
+
+func TestIteratorEmpty(t *testing.T) {
+	it := NewQueue[int]().Iterator()
+	if it.HasNext() {
+		t.Errorf("Expected HasNext to return false on empty queue")
+	}
+	if v := it.Next(); v != 0 {
+		t.Errorf("Expected zero value from exhausted iterator, got %v", v)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushBack(i)
+	}
+
+	c := q.Cursor()
+	if v, ok := c.Value(); !ok || v != 0 {
+		t.Errorf("Expected cursor value 0, got %v", v)
+	}
+
+	// Advance partway, then resume from the same position
+	c.Advance()
+	c.Advance()
+	if v, ok := c.Value(); !ok || v != 2 {
+		t.Errorf("Expected cursor value 2, got %v", v)
+	}
+	for want := 3; c.Advance(); want++ {
+		if v, ok := c.Value(); !ok || v != want {
+			t.Errorf("Expected cursor value %d, got %v", want, v)
+		}
+	}
+
+	// Past the end the cursor has no value
+	if _, ok := c.Value(); ok {
+		t.Errorf("Expected no value past the end of the queue")
+	}
+	if c.Advance() {
+		t.Errorf("Expected Advance to return false past the end")
+	}
+
+	// Walking the cursor must not consume the queue
+	if q.Len() != 5 {
+		t.Errorf("Expected queue length 5, got %d", q.Len())
+	}
+}
+
+func TestCursorEmpty(t *testing.T) {
+	c := NewQueue[int]().Cursor()
+	if _, ok := c.Value(); ok {
+		t.Errorf("Expected no value on empty queue")
+	}
+	if c.Advance() {
+		t.Errorf("Expected Advance to return false on empty queue")
+	}
+}
+
+// expectModifiedPanic fails the test unless fn panics with the fail-fast message.
+func expectModifiedPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != "bfq: queue modified during iteration" {
+			t.Errorf("Expected fail-fast panic, got %v", r)
+		}
+	}()
+	fn()
+}
+
+func TestIteratorFailFast(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushBack(i)
+	}
+
+	it := q.Iterator()
+	it.Next()
+	q.PopFront()
+	expectModifiedPanic(t, func() { it.Next() })
+
+	// Opting out lets the iterator follow the live contents
+	q.SetFailFast(false)
+	it = q.Iterator()
+	it.Next()
+	q.PushFront(-1)
+	if v := it.Next(); v != 1 {
+		t.Errorf("Expected value 1 from the live contents, got %v", v)
+	}
+}
+
+func TestReversedFailFast(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushBack(i)
+	}
+
+	expectModifiedPanic(t, func() {
+		for range q.Reversed() {
+			q.PushBack(100)
+		}
+	})
+
+	// With fail-fast disabled, popping during iteration ends it cleanly
+	q = NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushBack(i)
+	}
+	q.SetFailFast(false)
+	var got []int
+	for v := range q.Reversed() {
+		got = append(got, v)
+		q.PopBack()
+		q.PopBack()
+	}
+	if len(got) != 3 || got[0] != 4 || got[1] != 2 || got[2] != 0 {
+		t.Errorf("Expected [4 2 0], got %v", got)
+	}
+}
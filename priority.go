@@ -0,0 +1,31 @@
+package bfq
+
+// PriorityQueue orders its elements by a comparator using a binary heap
+// stored in a Queue, so it shares the Queue's growth and shrink behaviour.
+type PriorityQueue[T any] struct {
+	q    *Queue[T]
+	less func(a, b T) bool
+}
+
+// NewPriorityQueue creates an empty priority queue. Pop returns the element
+// for which less reports it is smallest.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{q: NewQueue[T](), less: less}
+}
+
+// Len returns the number of elements in the queue.
+func (pq *PriorityQueue[T]) Len() int { return pq.q.Len() }
+
+// IsEmpty checks if the queue is empty.
+func (pq *PriorityQueue[T]) IsEmpty() bool { return pq.q.IsEmpty() }
+
+// Push inserts an element according to its priority.
+func (pq *PriorityQueue[T]) Push(v T) {
+	HeapPush(pq.q, v, pq.less)
+}
+
+// Pop removes and returns the highest-priority element.
+func (pq *PriorityQueue[T]) Pop() (T, bool) { return HeapPop(pq.q, pq.less) }
+
+// Peek returns the highest-priority element without removing it.
+func (pq *PriorityQueue[T]) Peek() (T, bool) { return pq.q.Front() }
@@ -0,0 +1,65 @@
+package bfq
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	pq := NewPriorityQueue(func(a, b int) bool { return a < b })
+	if _, ok := pq.Pop(); ok {
+		t.Errorf("Expected Pop to return false on empty queue")
+	}
+	if _, ok := pq.Peek(); ok {
+		t.Errorf("Expected Peek to return false on empty queue")
+	}
+
+	r := rand.New(rand.NewSource(1))
+	items := make([]int, 500)
+	for i := range items {
+		items[i] = r.Intn(1000)
+		pq.Push(items[i])
+	}
+	sort.Ints(items)
+
+	if pq.Len() != len(items) {
+		t.Errorf("Expected queue length %d, got %d", len(items), pq.Len())
+	}
+	if top, ok := pq.Peek(); !ok || top != items[0] {
+		t.Errorf("Expected peeked value %d, got %v", items[0], top)
+	}
+
+	// Elements come out in priority order
+	for _, want := range items {
+		if val, ok := pq.Pop(); !ok || val != want {
+			t.Errorf("Expected popped value %d, got %v", want, val)
+		}
+	}
+	if !pq.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestPriorityQueueMaxHeap(t *testing.T) {
+	pq := NewPriorityQueue(func(a, b Data) bool { return a.ID > b.ID })
+	for _, id := range []int{3, 1, 4, 1, 5, 9, 2, 6} {
+		pq.Push(Data{ID: id})
+	}
+	for _, want := range []int{9, 6, 5, 4, 3, 2, 1, 1} {
+		if val, ok := pq.Pop(); !ok || val.ID != want {
+			t.Errorf("Expected popped ID %d, got %v", want, val.ID)
+		}
+	}
+}
+
+func BenchmarkPriorityQueuePushPop(b *testing.B) {
+	b.ReportAllocs()
+	pq := NewPriorityQueue(func(a, b int) bool { return a < b })
+	for i := 0; i < b.N; i++ {
+		pq.Push(b.N - i)
+	}
+	for i := 0; i < b.N; i++ {
+		pq.Pop()
+	}
+}
@@ -1,21 +1,52 @@
 package bfq
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 	"strings"
 	"unsafe"
 )
 
 // Queue represents a double-ended queue using a circular buffer.
 type Queue[T any] struct {
-	buf    []T
-	front  int
-	back   int
-	length int
+	buf     []T
+	front   int
+	back    int
+	length  int
+	growth  float64 // growth factor set by SetGrowthFactor; 0 means doubling
+	ratio   float64 // shrink ratio set by SetShrinkRatio; 0 means 1/4, negative disables
+	pushed  side    // end written by the most recent push
+	version uint64  // incremented by every mutation; see Version
+	lenient bool    // disables fail-fast iteration; see SetFailFast
+	onFirst func()  // called when a push makes the queue non-empty; see OnFirstElement
 }
 
+// side identifies one end of the queue.
+type side uint8
+
+const (
+	sideBack side = iota
+	sideFront
+)
+
 const (
 	minCapacity = 8
+	// maxCapacity is the largest power of two representable as an int.
+	maxCapacity = 1 << (bits.UintSize - 2)
+)
+
+// maxSize caps how large the buffer may grow. It equals maxCapacity and is
+// a variable only so tests can exercise the overflow guard.
+var maxSize = maxCapacity
+
+var (
+	// ErrNegativeCapacity is returned when a negative capacity is requested.
+	ErrNegativeCapacity = errors.New("bfq: negative capacity")
+	// ErrCapacityTooLarge is returned when a capacity cannot be rounded up
+	// to a power of two without overflowing int.
+	ErrCapacityTooLarge = errors.New("bfq: capacity too large")
 )
 
 // NewQueue creates an empty queue with an initial capacity.
@@ -23,6 +54,19 @@ func NewQueue[T any]() *Queue[T] {
 	return &Queue[T]{buf: make([]T, minCapacity)}
 }
 
+// NewQueueWithCapacity creates an empty queue able to hold at least capacity
+// elements before growing. The buffer is rounded up to a power of two; an
+// error is returned if capacity is negative or the rounding would overflow.
+func NewQueueWithCapacity[T any](capacity int) (*Queue[T], error) {
+	if capacity < 0 {
+		return nil, ErrNegativeCapacity
+	}
+	if capacity > maxSize {
+		return nil, ErrCapacityTooLarge
+	}
+	return &Queue[T]{buf: make([]T, nextPowerOfTwo(capacity))}, nil
+}
+
 // nextPowerOfTwo returns the smallest power of two greater than or equal to n.
 func nextPowerOfTwo(n int) int {
 	if n < minCapacity {
@@ -39,48 +83,246 @@ func nextPowerOfTwo(n int) int {
 	return n
 }
 
+// newSized returns a queue of n zero-valued elements laid out from the
+// start of a fresh buffer of the given size, for callers that fill buf[:n]
+// directly. size must be at least max(n, minCapacity).
+func newSized[T any](n, size int) *Queue[T] {
+	q := &Queue[T]{buf: make([]T, size), length: n}
+	q.back = q.wrap(n)
+	return q
+}
+
 // FromSlice creates a queue from a given slice, ensuring the buffer size is a power of two.
 func FromSlice[T any](slice []T) *Queue[T] {
-	size := nextPowerOfTwo(len(slice))
-	q := &Queue[T]{buf: make([]T, size), front: 0, back: len(slice), length: len(slice)}
+	q := newSized[T](len(slice), nextPowerOfTwo(len(slice)))
 	copy(q.buf, slice)
 	return q
 }
 
+// Of creates a queue holding items in order, with items[0] at the front.
+// The items are copied, so the queue never shares the caller's backing array.
+func Of[T any](items ...T) *Queue[T] {
+	return FromSlice(items)
+}
+
+// Tabulate creates a queue of length n whose element i, counted from the
+// front, is fn(i). The buffer is allocated once. A negative n yields an
+// empty queue.
+func Tabulate[T any](n int, fn func(i int) T) *Queue[T] {
+	n = max(n, 0)
+	q := newSized[T](n, nextPowerOfTwo(n))
+	for i := range n {
+		q.buf[i] = fn(i)
+	}
+	return q
+}
+
+// FromSliceReversed creates a queue holding the elements of slice in reverse
+// order, so that slice[0] ends up at the back.
+func FromSliceReversed[T any](slice []T) *Queue[T] {
+	q := newSized[T](len(slice), nextPowerOfTwo(len(slice)))
+	for i, v := range slice {
+		q.buf[len(slice)-1-i] = v
+	}
+	return q
+}
+
+// FromMapKeys creates a queue holding the keys of m.
+// The order of the keys is unspecified, following map iteration order.
+func FromMapKeys[K comparable, V any](m map[K]V) *Queue[K] {
+	q := &Queue[K]{buf: make([]K, nextPowerOfTwo(len(m)))}
+	for k := range m {
+		q.PushBack(k)
+	}
+	return q
+}
+
+// FromMapValues creates a queue holding the values of m.
+// The order of the values is unspecified, following map iteration order.
+func FromMapValues[K comparable, V any](m map[K]V) *Queue[V] {
+	q := &Queue[V]{buf: make([]V, nextPowerOfTwo(len(m)))}
+	for _, v := range m {
+		q.PushBack(v)
+	}
+	return q
+}
+
+// Version returns a counter that changes whenever the queue's contents are
+// modified, so observers can cheaply detect changes. Only equality between
+// two readings is meaningful; the counter may also advance on operations
+// that leave the contents equal, such as Apply.
+func (q *Queue[T]) Version() uint64 { return q.version }
+
 // Len returns the number of elements in the queue.
 func (q *Queue[T]) Len() int { return q.length }
 
 // IsEmpty checks if the queue is empty.
 func (q *Queue[T]) IsEmpty() bool { return q.length == 0 }
 
+// copyTo copies the elements in logical order into dst, handling the
+// wraparound with at most two copies. It returns the number of elements copied.
+func (q *Queue[T]) copyTo(dst []T) int {
+	end := q.front + q.length
+	if end <= len(q.buf) {
+		return copy(dst, q.buf[q.front:end])
+	}
+	n := copy(dst, q.buf[q.front:])
+	return n + copy(dst[n:], q.buf[:end-len(q.buf)])
+}
+
+// clone returns an independent shallow copy of the queue with the same
+// capacity and settings.
+func (q *Queue[T]) clone() *Queue[T] {
+	c := q.cloneShape()
+	q.copyTo(c.buf)
+	return c
+}
+
+// cloneShape returns a new queue with the same capacity, length and
+// settings (growth factor, shrink ratio and fail-fast mode) as q, whose
+// elements are still zero. Callers fill buf[:Len()] in logical order.
+func (q *Queue[T]) cloneShape() *Queue[T] {
+	c := newSized[T](q.length, len(q.buf))
+	c.growth, c.ratio, c.lenient = q.growth, q.ratio, q.lenient
+	return c
+}
+
+// span returns a new queue holding copies of the elements in the logical
+// range [start, end), sized to the next power of two. The range must be valid.
+func (q *Queue[T]) span(start, end int) *Queue[T] {
+	n := end - start
+	c := newSized[T](n, nextPowerOfTwo(n))
+	q.copyRange(c.buf, start, end)
+	return c
+}
+
+// copyRange copies the elements in the logical range [start, end) into dst
+// using at most two copies. The range must be valid.
+func (q *Queue[T]) copyRange(dst []T, start, end int) int {
+	from := q.wrap(q.front + start)
+	to := from + end - start
+	if to <= len(q.buf) {
+		return copy(dst, q.buf[from:to])
+	}
+	n := copy(dst, q.buf[from:])
+	return n + copy(dst[n:], q.buf[:to-len(q.buf)])
+}
+
 // resize resizes the queue when needed.
 func (q *Queue[T]) resize(size int) {
 	newBuf := make([]T, size)
-	if q.front < q.back {
-		copy(newBuf, q.buf[q.front:q.back])
-	} else {
-		n := copy(newBuf, q.buf[q.front:])
-		copy(newBuf[n:], q.buf[:q.back])
-	}
+	q.copyTo(newBuf)
 	q.buf = newBuf
 	q.front = 0
-	q.back = q.length
+	q.back = q.wrap(q.length)
+}
+
+// linearize makes the elements contiguous in the buffer and returns them as
+// a slice aliasing it. A wrapped-around queue is copied into a fresh buffer.
+func (q *Queue[T]) linearize() []T {
+	if q.front+q.length > len(q.buf) {
+		q.resize(len(q.buf))
+	}
+	return q.buf[q.front : q.front+q.length]
 }
 
 // grow expands the queue when full.
 func (q *Queue[T]) grow() {
 	if q.length == len(q.buf) {
-		q.resize(len(q.buf) << 1)
+		q.resize(q.grownSize())
+	}
+}
+
+// grownSize returns the buffer size to grow to under the growth factor,
+// capped at maxSize. It panics if the buffer is already at maxSize, since
+// doubling further would overflow int.
+func (q *Queue[T]) grownSize() int {
+	size := len(q.buf)
+	if size >= maxSize {
+		panic("bfq: queue too large")
+	}
+	if q.growth == 0 {
+		return min(size<<1, maxSize)
+	}
+	if float64(size)*q.growth >= float64(maxSize) {
+		return maxSize
+	}
+	return max(int(math.Ceil(float64(size)*q.growth)), size+1)
+}
+
+// SetGrowthFactor sets how much the buffer grows when it is full. The
+// default is 2. Smaller factors such as 1.5 waste less memory at the cost
+// of more frequent resizes; larger factors trade memory for fewer resizes.
+// The grown size is rounded up to a whole number of elements. Factors that
+// are not greater than 1 are ignored.
+func (q *Queue[T]) SetGrowthFactor(factor float64) {
+	if factor > 1 {
+		q.growth = factor
+	}
+}
+
+// SetCapacity resizes the buffer to the smallest power of two that holds n
+// elements, preserving element order. It returns false and leaves the queue
+// unchanged if n is smaller than Len() or too large to allocate.
+func (q *Queue[T]) SetCapacity(n int) bool {
+	if n < q.length || n > maxSize {
+		return false
+	}
+	if size := nextPowerOfTwo(n); size != len(q.buf) {
+		q.resize(size)
+	}
+	return true
+}
+
+// reserve ensures the buffer can hold n more elements without growing. It
+// grows by the growth factor when that is enough, and otherwise straight to
+// the next power of two holding them.
+func (q *Queue[T]) reserve(n int) {
+	if n > maxSize-q.length {
+		panic("bfq: queue too large")
+	}
+	if need := q.length + n; need > len(q.buf) {
+		size := q.grownSize()
+		if size < need {
+			size = nextPowerOfTwo(need)
+		}
+		q.resize(size)
 	}
 }
 
 // shrink reduces memory usage when necessary.
 func (q *Queue[T]) shrink() {
-	if q.length > minCapacity && q.length == len(q.buf) >> 2 {
+	if q.length > minCapacity && q.length == q.shrinkThreshold(len(q.buf)) {
 		q.resize(len(q.buf) >> 1)
 	}
 }
 
+// shrinkThreshold returns the length at which a buffer of the given size is
+// halved, or -1 if shrinking is disabled.
+func (q *Queue[T]) shrinkThreshold(size int) int {
+	switch {
+	case q.ratio == 0:
+		return size >> 2
+	case q.ratio < 0:
+		return -1
+	}
+	return int(float64(size) * q.ratio)
+}
+
+// SetShrinkRatio sets how empty the buffer must get before it is halved:
+// it shrinks once Len() falls to ratio times the capacity. The default is
+// 0.25. Lower ratios resize less often at the cost of memory; a ratio of 0
+// disables shrinking altogether. Ratios outside [0, 0.5) are ignored, since
+// at 0.5 or above a halved buffer could immediately need to grow again.
+func (q *Queue[T]) SetShrinkRatio(ratio float64) {
+	switch {
+	case ratio == 0:
+		q.ratio = -1
+	case ratio > 0 && ratio < 0.5:
+		q.ratio = ratio
+	}
+}
+
 // indexUnsafe gets the pointer to an element without bounds checks.
 func (q *Queue[T]) indexUnsafe(index int) *T {
 	base := unsafe.Pointer(&q.buf[0]) // Base address of buffer
@@ -88,20 +330,193 @@ func (q *Queue[T]) indexUnsafe(index int) *T {
 	return (*T)(unsafe.Pointer(uintptr(base) + uintptr(index)*size))
 }
 
+// wrap maps a buffer position that has run at most one buffer length past
+// either end back into [0, len(q.buf)). A growth factor below 2 produces
+// buffer sizes that are not powers of two, so positions are wrapped by
+// comparison rather than by masking with the size.
+func (q *Queue[T]) wrap(i int) int {
+	if i >= len(q.buf) {
+		return i - len(q.buf)
+	}
+	if i < 0 {
+		return i + len(q.buf)
+	}
+	return i
+}
+
+// at returns a pointer to the element at logical index i, counted from the front.
+func (q *Queue[T]) at(i int) *T {
+	return q.indexUnsafe(q.wrap(q.front + i))
+}
+
+// compact shrinks the buffer after a bulk removal, halving it for as long as
+// the remaining elements occupy no more than a quarter of it.
+func (q *Queue[T]) compact() {
+	size := len(q.buf)
+	for q.length > minCapacity && q.length <= q.shrinkThreshold(size) {
+		size >>= 1
+	}
+	if size != len(q.buf) {
+		q.resize(size)
+	}
+}
+
+// discardFront removes k elements from the front, zeroing their slots.
+// It does not shrink the buffer; callers follow up with compact.
+func (q *Queue[T]) discardFront(k int) {
+	var zero T
+	for i := 0; i < k; i++ {
+		*q.at(i) = zero
+	}
+	q.front = q.wrap(q.front + k)
+	q.length -= k
+	q.version++
+}
+
+// discardBack removes k elements from the back, zeroing their slots.
+// It does not shrink the buffer; callers follow up with compact.
+func (q *Queue[T]) discardBack(k int) {
+	var zero T
+	for i := q.length - k; i < q.length; i++ {
+		*q.at(i) = zero
+	}
+	q.back = q.wrap(q.back - k)
+	q.length -= k
+	q.version++
+}
+
+// rotate moves the element at logical index k to the front while keeping
+// the cyclic order of all elements. A full buffer rotates in O(1) by moving
+// the indices; otherwise the shorter side is moved one element at a time.
+func (q *Queue[T]) rotate(k int) {
+	if q.length == 0 {
+		return
+	}
+	k %= q.length
+	q.version++
+	if q.length == len(q.buf) {
+		q.front = q.wrap(q.front + k)
+		q.back = q.front
+		return
+	}
+	var zero T
+	if k <= q.length-k {
+		for ; k > 0; k-- {
+			*q.indexUnsafe(q.back) = *q.indexUnsafe(q.front)
+			*q.indexUnsafe(q.front) = zero
+			q.back = q.wrap(q.back + 1)
+			q.front = q.wrap(q.front + 1)
+		}
+	} else {
+		for k = q.length - k; k > 0; k-- {
+			q.back = q.wrap(q.back - 1)
+			q.front = q.wrap(q.front - 1)
+			*q.indexUnsafe(q.front) = *q.indexUnsafe(q.back)
+			*q.indexUnsafe(q.back) = zero
+		}
+	}
+}
+
 // PushFront inserts an element at the front.
 func (q *Queue[T]) PushFront(v T) {
 	q.grow()
-	q.front = (q.front - 1 + len(q.buf)) & (len(q.buf) - 1)
+	q.front = q.wrap(q.front - 1)
 	*(*T)(unsafe.Pointer(q.indexUnsafe(q.front))) = v
 	q.length++
+	q.pushed = sideFront
+	q.version++
+	q.notifyFirst(q.length - 1)
 }
 
 // PushBack inserts an element at the back.
 func (q *Queue[T]) PushBack(v T) {
 	q.grow()
 	*(*T)(unsafe.Pointer(q.indexUnsafe(q.back))) = v
-	q.back = (q.back + 1) & (len(q.buf) - 1)
+	q.back = q.wrap(q.back + 1)
 	q.length++
+	q.pushed = sideBack
+	q.version++
+	q.notifyFirst(q.length - 1)
+}
+
+// appendSlice copies s to the back of the queue, reserving capacity once
+// and handling the wraparound with at most two copies.
+func (q *Queue[T]) appendSlice(s []T) {
+	q.reserve(len(s))
+	n := copy(q.buf[q.back:], s)
+	copy(q.buf, s[n:])
+	q.back = q.wrap(q.back + len(s))
+	q.length += len(s)
+	q.pushed = sideBack
+	q.version++
+	q.notifyFirst(q.length - len(s))
+}
+
+// notifyFirst calls the OnFirstElement callback if an insertion has taken
+// the queue from prev == 0 elements to a non-empty state. Every path that
+// adds elements to an existing queue calls it once the insertion is done.
+func (q *Queue[T]) notifyFirst(prev int) {
+	if prev == 0 && q.length > 0 && q.onFirst != nil {
+		q.onFirst()
+	}
+}
+
+// PushBackSlice inserts s[start:end] at the back in one reserved-capacity
+// operation. It returns false and leaves the queue unchanged if the bounds
+// are not valid for s.
+func (q *Queue[T]) PushBackSlice(s []T, start, end int) bool {
+	if start < 0 || start > end || end > len(s) {
+		return false
+	}
+	q.appendSlice(s[start:end])
+	return true
+}
+
+// PushBackLen inserts an element at the back and returns the new length.
+func (q *Queue[T]) PushBackLen(v T) int {
+	q.PushBack(v)
+	return q.length
+}
+
+// PushBackBounded inserts an element at the back only if the queue holds
+// fewer than maxLen elements. It returns false, without growing the buffer,
+// if the queue is already at its limit.
+func (q *Queue[T]) PushBackBounded(v T, maxLen int) bool {
+	if q.length >= maxLen {
+		return false
+	}
+	q.PushBack(v)
+	return true
+}
+
+// PushBackEvict inserts v at the back of a queue bounded to maxLen
+// elements, the core step of a FIFO cache. If the queue is already at its
+// limit, the front element is removed first, its slot zeroed, and returned
+// as evicted. A maxLen of 0 or less admits nothing: v itself is returned as
+// evicted and the queue is left unchanged.
+//
+// At most one element is evicted per call, so a queue that is already
+// longer than maxLen keeps its length rather than being trimmed to the
+// limit; use RetainLast to trim it first.
+func (q *Queue[T]) PushBackEvict(v T, maxLen int) (evicted T, didEvict bool) {
+	if maxLen <= 0 {
+		return v, true
+	}
+	if q.length >= maxLen {
+		evicted, didEvict = *q.at(0), true
+		q.discardFront(1)
+	}
+	q.PushBack(v)
+	return evicted, didEvict
+}
+
+// PushBackResized inserts an element at the back and reports whether the
+// push had to grow the buffer, letting latency-sensitive callers account
+// for the occasional expensive resize.
+func (q *Queue[T]) PushBackResized(v T) bool {
+	resized := q.length == len(q.buf)
+	q.PushBack(v)
+	return resized
 }
 
 // PopFront removes and returns the front element.
@@ -111,8 +526,9 @@ func (q *Queue[T]) PopFront() (T, bool) {
 		return zero, false
 	}
 	v := *q.indexUnsafe(q.front)
-	q.front = (q.front + 1) & (len(q.buf) - 1)
+	q.front = q.wrap(q.front + 1)
 	q.length--
+	q.version++
 	q.shrink()
 	return v, true
 }
@@ -123,13 +539,32 @@ func (q *Queue[T]) PopBack() (T, bool) {
 		var zero T
 		return zero, false
 	}
-	q.back = (q.back - 1 + len(q.buf)) & (len(q.buf) - 1)
+	q.back = q.wrap(q.back - 1)
 	v := *q.indexUnsafe(q.back)
 	q.length--
+	q.version++
 	q.shrink()
 	return v, true
 }
 
+// PopFrontOrDefault removes and returns the front element, or def if the
+// queue is empty.
+func (q *Queue[T]) PopFrontOrDefault(def T) T {
+	if v, ok := q.PopFront(); ok {
+		return v
+	}
+	return def
+}
+
+// PopBackOrDefault removes and returns the back element, or def if the
+// queue is empty.
+func (q *Queue[T]) PopBackOrDefault(def T) T {
+	if v, ok := q.PopBack(); ok {
+		return v
+	}
+	return def
+}
+
 // Front returns the first element without removing it.
 func (q *Queue[T]) Front() (T, bool) {
 	if q.IsEmpty() {
@@ -145,9 +580,96 @@ func (q *Queue[T]) Back() (T, bool) {
 		var zero T
 		return zero, false
 	}
-	return *q.indexUnsafe((q.back - 1 + len(q.buf)) & (len(q.buf) - 1)), true
+	return *q.indexUnsafe(q.wrap(q.back - 1)), true
+}
+
+// FrontOr returns the first element, or def if the queue is empty.
+func (q *Queue[T]) FrontOr(def T) T {
+	if v, ok := q.Front(); ok {
+		return v
+	}
+	return def
+}
+
+// BackOr returns the last element, or def if the queue is empty.
+func (q *Queue[T]) BackOr(def T) T {
+	if v, ok := q.Back(); ok {
+		return v
+	}
+	return def
+}
+
+// At returns the element at logical index i, counted from the front. It
+// returns false if i is out of range.
+func (q *Queue[T]) At(i int) (T, bool) {
+	if !q.ValidIndex(i) {
+		var zero T
+		return zero, false
+	}
+	return *q.at(i), true
+}
+
+// Set replaces the element at logical index i with v. It returns false and
+// leaves the queue unchanged if i is out of range.
+func (q *Queue[T]) Set(i int, v T) bool {
+	if !q.ValidIndex(i) {
+		return false
+	}
+	*q.at(i) = v
+	q.version++
+	return true
+}
+
+// AtFromBack returns the element n positions from the back, where n=0 is
+// the back element. It returns false if n is out of range.
+func (q *Queue[T]) AtFromBack(n int) (T, bool) {
+	if !q.ValidIndex(n) {
+		var zero T
+		return zero, false
+	}
+	return *q.at(q.length - 1 - n), true
+}
+
+// FrontIndex returns the logical index of the front element, which is always 0.
+func (q *Queue[T]) FrontIndex() int { return 0 }
+
+// BackIndex returns the logical index of the back element, Len()-1.
+// It is -1 for an empty queue.
+func (q *Queue[T]) BackIndex() int { return q.length - 1 }
+
+// ValidIndex reports whether i refers to an element, that is 0 <= i < Len().
+// Logical indices count from the front, regardless of where the front sits
+// in the underlying buffer.
+func (q *Queue[T]) ValidIndex(i int) bool { return i >= 0 && i < q.length }
+
+// LastPushed returns the element at the end that the most recent insertion
+// at an end wrote to. That covers PushFront and PushBack as well as bulk
+// insertions such as PushBackSlice, PushFrontSeq and InsertAtAll at index 0
+// or Len(); insertions in the middle leave it unchanged. If that element has
+// since been popped, the current element at the same end is returned. It
+// returns false if the queue is empty.
+func (q *Queue[T]) LastPushed() (T, bool) {
+	if q.pushed == sideFront {
+		return q.Front()
+	}
+	return q.Back()
 }
 
+// Ends returns both the front and back elements. For a single-element queue
+// front and back are the same value; ok is false if the queue is empty.
+func (q *Queue[T]) Ends() (front T, back T, ok bool) {
+	if q.IsEmpty() {
+		return front, back, false
+	}
+	return *q.at(0), *q.at(q.length - 1), true
+}
+
+// PeekFront is an alias for Front.
+func (q *Queue[T]) PeekFront() (T, bool) { return q.Front() }
+
+// PeekBack is an alias for Back.
+func (q *Queue[T]) PeekBack() (T, bool) { return q.Back() }
+
 // String returns a string representation of the queue.
 func (q *Queue[T]) String() string {
 	var sb strings.Builder
@@ -157,8 +679,579 @@ func (q *Queue[T]) String() string {
 			sb.WriteByte(' ')
 		}
 		sb.WriteString(fmt.Sprintf("%v", *q.indexUnsafe(idx)))
-		idx = (idx + 1) & (len(q.buf) - 1)
+		idx = q.wrap(idx + 1)
 	}
 	sb.WriteByte(']')
 	return sb.String()
 }
+
+// FilterInPlace keeps only the elements for which pred returns true,
+// compacting them toward the front without allocating a new queue.
+// Freed slots are zeroed. It returns the number of elements kept.
+func (q *Queue[T]) FilterInPlace(pred func(T) bool) int {
+	kept := 0
+	for i := 0; i < q.length; i++ {
+		v := *q.at(i)
+		if pred(v) {
+			*q.at(kept) = v
+			kept++
+		}
+	}
+	var zero T
+	for i := kept; i < q.length; i++ {
+		*q.at(i) = zero
+	}
+	q.back = q.wrap(q.front + kept)
+	q.length = kept
+	q.version++
+	q.compact()
+	return kept
+}
+
+// InsertAtAll inserts items at logical index, shifting the shorter side of
+// the queue to make room. Capacity is reserved once for the whole batch.
+// An index equal to Len() appends; it returns false if index is out of range.
+func (q *Queue[T]) InsertAtAll(index int, items ...T) bool {
+	if index < 0 || index > q.length {
+		return false
+	}
+	k := len(items)
+	if k == 0 {
+		return true
+	}
+	q.reserve(k)
+	q.version++
+	switch index {
+	case 0:
+		q.pushed = sideFront
+	case q.length:
+		q.pushed = sideBack
+	}
+	if index < q.length-index {
+		q.front = q.wrap(q.front - k)
+		q.length += k
+		for i := 0; i < index; i++ {
+			*q.at(i) = *q.at(i + k)
+		}
+	} else {
+		n := q.length
+		q.length += k
+		q.back = q.wrap(q.back + k)
+		for i := n - 1; i >= index; i-- {
+			*q.at(i + k) = *q.at(i)
+		}
+	}
+	for i, v := range items {
+		*q.at(index + i) = v
+	}
+	q.notifyFirst(q.length - k)
+	return true
+}
+
+// MoveToFront moves the element at logical index to the front, shifting the
+// elements before it back by one. It returns false if index is out of range.
+func (q *Queue[T]) MoveToFront(index int) bool {
+	if !q.ValidIndex(index) {
+		return false
+	}
+	q.version++
+	v := *q.at(index)
+	for i := index; i > 0; i-- {
+		*q.at(i) = *q.at(i - 1)
+	}
+	*q.at(0) = v
+	return true
+}
+
+// MoveToBack moves the element at logical index to the back, shifting the
+// elements after it forward by one. It returns false if index is out of range.
+func (q *Queue[T]) MoveToBack(index int) bool {
+	if !q.ValidIndex(index) {
+		return false
+	}
+	q.version++
+	v := *q.at(index)
+	for i := index; i < q.length-1; i++ {
+		*q.at(i) = *q.at(i + 1)
+	}
+	*q.at(q.length - 1) = v
+	return true
+}
+
+// Validate checks the internal invariants of the queue and returns a
+// descriptive error for the first one that does not hold. It is intended
+// for tests and debugging after sequences of operations.
+func (q *Queue[T]) Validate() error {
+	size := len(q.buf)
+	if size < minCapacity {
+		return fmt.Errorf("bfq: capacity %d is below the minimum %d", size, minCapacity)
+	}
+	if q.length < 0 || q.length > size {
+		return fmt.Errorf("bfq: length %d out of range [0, %d]", q.length, size)
+	}
+	if q.front < 0 || q.front >= size {
+		return fmt.Errorf("bfq: front index %d out of range [0, %d)", q.front, size)
+	}
+	if q.back < 0 || q.back >= size {
+		return fmt.Errorf("bfq: back index %d out of range [0, %d)", q.back, size)
+	}
+	if d := q.wrap(q.back - q.front); d != q.length%size {
+		return fmt.Errorf("bfq: distance %d between front %d and back %d does not match length %d", d, q.front, q.back, q.length)
+	}
+	return nil
+}
+
+// ScanPairs calls fn with pairs of elements converging from both ends:
+// (first, last), (second, second-to-last), and so on. The middle element of
+// an odd-length queue is not visited. Scanning stops early if fn returns false.
+// It panics if fn modifies the queue, unless fail-fast iteration has been
+// disabled with SetFailFast.
+func (q *Queue[T]) ScanPairs(fn func(left, right T) bool) {
+	version := q.version
+	for i, j := 0, q.length-1; i < j; i, j = i+1, min(j-1, q.length-1) {
+		if !fn(*q.at(i), *q.at(j)) {
+			return
+		}
+		q.checkVersion(version)
+	}
+}
+
+// IsPalindrome reports whether the queue reads the same from both ends
+// according to eq. Empty and single-element queues are palindromes.
+func (q *Queue[T]) IsPalindrome(eq func(a, b T) bool) bool {
+	ok := true
+	q.ScanPairs(func(left, right T) bool {
+		ok = eq(left, right)
+		return ok
+	})
+	return ok
+}
+
+// Tee returns two independent copies of the queue so that two consumers can
+// drain their own copy. The source is left intact. Elements are copied
+// shallowly: pointers, slices and maps still refer to the same data.
+func (q *Queue[T]) Tee() (*Queue[T], *Queue[T]) {
+	return q.clone(), q.clone()
+}
+
+// RetainFirst keeps only the first n elements, dropping the rest from the
+// back and zeroing their slots. It is a no-op if n >= Len().
+func (q *Queue[T]) RetainFirst(n int) {
+	if n >= q.length {
+		return
+	}
+	q.discardBack(q.length - max(n, 0))
+	q.compact()
+}
+
+// RetainLast keeps only the last n elements, dropping the rest from the
+// front and zeroing their slots. It is a no-op if n >= Len().
+func (q *Queue[T]) RetainLast(n int) {
+	if n >= q.length {
+		return
+	}
+	q.discardFront(q.length - max(n, 0))
+	q.compact()
+}
+
+// PopFrontWhile removes elements from the front for as long as they satisfy
+// pred and returns them in order. The first non-matching element stays in
+// the queue. Freed slots are zeroed and the buffer is shrunk once at the end.
+func (q *Queue[T]) PopFrontWhile(pred func(T) bool) []T {
+	n := 0
+	for n < q.length && pred(*q.at(n)) {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	items := make([]T, n)
+	q.copyTo(items)
+	q.discardFront(n)
+	q.compact()
+	return items
+}
+
+// PopFrontUntil drains an expiry queue: with entries sorted by deadline and
+// a cmp that reports whether an entry has expired, it pops and returns every
+// expired entry in one call, stopping at the first entry that has not
+// expired. Note that cmp reports the entries to remove, so the popping
+// continues while cmp is true; it is PopFrontWhile under the name expiry
+// code reaches for.
+func (q *Queue[T]) PopFrontUntil(cmp func(T) bool) []T {
+	return q.PopFrontWhile(cmp)
+}
+
+// CopyInto replaces the contents of dst with a shallow copy of q's elements,
+// reusing dst's buffer when it is large enough. Unused slots in dst are
+// zeroed so they don't retain stale references.
+func (q *Queue[T]) CopyInto(dst *Queue[T]) {
+	if dst == q {
+		return
+	}
+	if len(dst.buf) < q.length {
+		dst.buf = make([]T, nextPowerOfTwo(q.length))
+	}
+	n := q.copyTo(dst.buf)
+	clear(dst.buf[n:])
+	dst.front = 0
+	dst.back = dst.wrap(n)
+	dst.length = n
+	dst.version++
+}
+
+// SizeInBytes returns an estimate of the memory held by the queue: the
+// buffer's capacity times the element size plus the Queue struct itself.
+// It does not follow pointers, so memory referenced by elements (strings,
+// slices, maps, pointed-to structs) is not included.
+func (q *Queue[T]) SizeInBytes() uintptr {
+	var zero T
+	return uintptr(len(q.buf))*unsafe.Sizeof(zero) + unsafe.Sizeof(*q)
+}
+
+// HeadTail returns the front element and a new queue holding the remaining
+// elements, leaving q unmodified. ok is false if the queue is empty.
+func (q *Queue[T]) HeadTail() (head T, tail *Queue[T], ok bool) {
+	if q.IsEmpty() {
+		return head, nil, false
+	}
+	tail = q.clone()
+	tail.discardFront(1)
+	return *q.at(0), tail, true
+}
+
+// ReplaceFunc replaces every element with the result of fn applied to it,
+// in place and without allocating. It panics if fn modifies the queue,
+// unless fail-fast iteration has been disabled with SetFailFast.
+func (q *Queue[T]) ReplaceFunc(fn func(T) T) {
+	q.version++
+	version := q.version
+	for i := 0; i < q.length; i++ {
+		v := fn(*q.at(i))
+		q.checkVersion(version)
+		if i < q.length {
+			*q.at(i) = v
+		}
+	}
+}
+
+// ForEachChunk calls fn with successive front-to-back chunks of up to size
+// elements. A single scratch slice is reused for every call, so fn must not
+// retain it. It does nothing if size is less than 1. It panics if fn
+// modifies the queue, unless fail-fast iteration has been disabled with
+// SetFailFast.
+func (q *Queue[T]) ForEachChunk(size int, fn func([]T)) {
+	if size < 1 || q.length == 0 {
+		return
+	}
+	version := q.version
+	scratch := make([]T, min(size, q.length))
+	for start := 0; start < q.length; start += size {
+		chunk := scratch[:min(size, q.length-start)]
+		for i := range chunk {
+			chunk[i] = *q.at(start + i)
+		}
+		fn(chunk)
+		q.checkVersion(version)
+	}
+}
+
+// SplitFunc splits the queue at the first element satisfying pred: before
+// holds the elements preceding it and after holds the match and everything
+// following it. If no element matches, before holds all elements, after is
+// empty and found is false. The returned queues are independent copies and
+// q is not modified.
+func (q *Queue[T]) SplitFunc(pred func(T) bool) (before, after *Queue[T], found bool) {
+	i := 0
+	for i < q.length && !pred(*q.at(i)) {
+		i++
+	}
+	return q.span(0, i), q.span(i, q.length), i < q.length
+}
+
+// FindLast scans from back to front and returns the logical index and value
+// of the last element satisfying pred. The index counts from the front, as
+// with the other index methods. It returns -1 and false if nothing matches.
+func (q *Queue[T]) FindLast(pred func(T) bool) (index int, value T, ok bool) {
+	for i := q.length - 1; i >= 0; i-- {
+		if v := *q.at(i); pred(v) {
+			return i, v, true
+		}
+	}
+	return -1, value, false
+}
+
+// Steal compacts the elements to the start of the backing buffer and hands
+// it to the caller as a slice of length Len(), without a final copy. If the
+// elements wrap around the end of the buffer they are instead copied out to
+// a new buffer of the same capacity in two segments. The queue is reset to
+// a fresh minimum-capacity buffer and no longer refers to the returned
+// slice, which the caller now owns.
+func (q *Queue[T]) Steal() []T {
+	items := q.buf
+	if q.front+q.length > len(q.buf) {
+		items = make([]T, len(q.buf))
+		q.copyTo(items)
+	} else if q.front != 0 {
+		copy(items, q.buf[q.front:q.front+q.length])
+		clear(items[q.length:])
+	}
+	items = items[:q.length]
+	q.buf, q.front, q.back, q.length = make([]T, minCapacity), 0, 0, 0
+	q.version++
+	return items
+}
+
+// Apply calls fn with a pointer to each element, front to back, so fields of
+// struct elements can be mutated in place. The pointers refer to the live
+// buffer and must not be retained past the call, since a later resize moves
+// the elements. It panics if fn modifies the queue itself, unless fail-fast
+// iteration has been disabled with SetFailFast.
+func (q *Queue[T]) Apply(fn func(*T)) {
+	q.version++
+	version := q.version
+	for i := 0; i < q.length; i++ {
+		fn(q.at(i))
+		q.checkVersion(version)
+	}
+}
+
+// PopEnds pops up to n elements from the front and up to n from the back in
+// one call. fronts is in front-to-back order and backs in the order the
+// elements were popped, starting with the back element. When the queue
+// holds fewer than 2n elements the front is served first and the back gets
+// whatever remains, so no element is popped twice.
+func (q *Queue[T]) PopEnds(n int) (fronts, backs []T) {
+	n = max(n, 0)
+	fronts = make([]T, min(n, q.length))
+	q.copyTo(fronts)
+	q.discardFront(len(fronts))
+	backs = make([]T, min(n, q.length))
+	for i := range backs {
+		backs[i] = *q.at(q.length - 1 - i)
+	}
+	q.discardBack(len(backs))
+	q.compact()
+	return fronts, backs
+}
+
+// SetContents replaces all elements with a copy of s, with s[0] at the
+// front. The buffer is reused when it is large enough, and any slots left
+// over are zeroed.
+func (q *Queue[T]) SetContents(s []T) {
+	if len(q.buf) < len(s) {
+		q.buf = make([]T, nextPowerOfTwo(len(s)))
+	}
+	prev := q.length
+	n := copy(q.buf, s)
+	clear(q.buf[n:])
+	q.front = 0
+	q.back = q.wrap(n)
+	q.length = n
+	q.version++
+	q.notifyFirst(prev)
+}
+
+// LongestRun returns the start index and length of the longest run of
+// adjacent elements that are equal according to eq. Ties go to the run
+// closest to the front. An empty queue returns (0, 0).
+func (q *Queue[T]) LongestRun(eq func(a, b T) bool) (start, length int) {
+	runStart := 0
+	for i := 1; i <= q.length; i++ {
+		if i < q.length && eq(*q.at(i - 1), *q.at(i)) {
+			continue
+		}
+		if i-runStart > length {
+			start, length = runStart, i-runStart
+		}
+		runStart = i
+	}
+	return start, length
+}
+
+// SetRange overwrites the elements at logical indices
+// [start, start+len(values)) with values. It returns false and leaves the
+// queue unchanged if the range extends outside the queue.
+func (q *Queue[T]) SetRange(start int, values []T) bool {
+	if start < 0 || start > q.length-len(values) {
+		return false
+	}
+	// The target range wraps at most once, so it takes one or two copies.
+	from := q.wrap(q.front + start)
+	n := copy(q.buf[from:], values)
+	copy(q.buf, values[n:])
+	q.version++
+	return true
+}
+
+// GetRange returns a copy of the elements at logical indices [start, end),
+// in order. It returns false if start > end or the range extends outside
+// the queue.
+func (q *Queue[T]) GetRange(start, end int) ([]T, bool) {
+	if start < 0 || end < start || end > q.length {
+		return nil, false
+	}
+	out := make([]T, end-start)
+	q.copyRange(out, start, end)
+	return out, true
+}
+
+// SubQueue returns a new independent queue holding the elements at logical
+// indices [start, end). The source is not modified. It returns nil if
+// start > end or the range extends outside the queue.
+func (q *Queue[T]) SubQueue(start, end int) *Queue[T] {
+	if start < 0 || end < start || end > q.length {
+		return nil
+	}
+	return q.span(start, end)
+}
+
+// LoadFactor returns the fraction of the buffer in use, Len() divided by
+// the current capacity. As it approaches 1 the next push will resize, so
+// producers can consult it to throttle or batch. The capacity is a power
+// of two unless a growth factor is set, so the value only has meaning
+// relative to that buffer size; it drops sharply after every growth.
+func (q *Queue[T]) LoadFactor() float64 {
+	return float64(q.length) / float64(len(q.buf))
+}
+
+// InsertByKey inserts v so that a queue sorted ascending by key stays
+// sorted, as a lightweight alternative to a heap for small schedules. v goes
+// after any elements with an equal key, so ties keep their insertion order.
+// The position is found by binary search; the result is undefined if the
+// queue is not already sorted by key.
+func (q *Queue[T]) InsertByKey(v T, key func(T) int64) {
+	k := key(v)
+	lo, hi := 0, q.length
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if key(*q.at(mid)) <= k {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	q.InsertAtAll(lo, v)
+}
+
+// SharesBuffer reports whether a and b are backed by the same array. It is
+// meant primarily for tests and diagnostics, to catch accidental aliasing
+// between queues produced by copying or zero-copy operations.
+func SharesBuffer[T any](a, b *Queue[T]) bool {
+	if len(a.buf) == 0 || len(b.buf) == 0 {
+		return false
+	}
+	return unsafe.SliceData(a.buf) == unsafe.SliceData(b.buf)
+}
+
+// Next returns the front element and moves it to the back, so repeated calls
+// cycle through all elements in order, the basic round-robin step. It never
+// grows the buffer. It returns false only if the queue is empty.
+func (q *Queue[T]) Next() (T, bool) {
+	if q.length == 0 {
+		var zero T
+		return zero, false
+	}
+	v := *q.at(0)
+	q.rotate(1)
+	return v, true
+}
+
+// TransformFrontN removes up to n elements from the front, applies fn to
+// each and pushes the results to the back in the same order, modelling a
+// worker that processes a batch and requeues it. It returns the number of
+// elements processed. The buffer never grows.
+func (q *Queue[T]) TransformFrontN(n int, fn func(T) T) int {
+	n = max(0, min(n, q.length))
+	for i := range n {
+		p := q.at(i)
+		*p = fn(*p)
+	}
+	if n > 0 {
+		q.rotate(n)
+	}
+	return n
+}
+
+// Transaction runs fn on q and commits its changes only if fn returns nil.
+// If fn returns an error or panics, q is restored to its exact state before
+// the call, then the error is returned or the panic continues. The snapshot
+// costs one copy of the buffer. The version still advances on rollback, so
+// iterators created inside fn see the queue as modified.
+func (q *Queue[T]) Transaction(fn func(*Queue[T]) error) (err error) {
+	saved := *q
+	saved.buf = make([]T, len(q.buf))
+	copy(saved.buf, q.buf)
+	committed := false
+	defer func() {
+		if !committed {
+			v := q.version
+			*q = saved
+			q.version = v + 1
+		}
+	}()
+	if err = fn(q); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// OnFirstElement registers fn to be called whenever an insertion makes an
+// empty queue non-empty, whether by a single push, a bulk insertion or
+// SetContents, so a consumer can be woken exactly when work first appears
+// instead of polling. It does not fire for insertions into a queue that
+// already holds elements. A nil fn removes the callback.
+func (q *Queue[T]) OnFirstElement(fn func()) {
+	q.onFirst = fn
+}
+
+// DrainTo moves up to len(dst) elements from the front of the queue into
+// dst, in order, and returns how many were moved. The vacated slots are
+// zeroed and the buffer is shrunk once at the end, so a consumer can drain
+// the queue in chunks through one reusable scratch slice.
+func (q *Queue[T]) DrainTo(dst []T) int {
+	n := min(len(dst), q.length)
+	if n == 0 {
+		return 0
+	}
+	q.copyRange(dst, 0, n)
+	q.discardFront(n)
+	q.compact()
+	return n
+}
+
+// ToSlice returns a new slice holding the elements in front-to-back order.
+// The queue is not modified.
+func (q *Queue[T]) ToSlice() []T {
+	out := make([]T, q.length)
+	q.copyTo(out)
+	return out
+}
+
+// ToReversedSlice returns a new slice holding the elements in back-to-front
+// order, filled in a single pass. The queue is not modified.
+func (q *Queue[T]) ToReversedSlice() []T {
+	out := make([]T, q.length)
+	for i, idx := q.length-1, q.front; i >= 0; i-- {
+		out[i] = *q.indexUnsafe(idx)
+		idx = q.wrap(idx + 1)
+	}
+	return out
+}
+
+// PopAll removes every element satisfying pred, wherever it sits, and
+// returns them in their original order. The remaining elements keep their
+// order and are compacted as with FilterInPlace. It returns nil if nothing
+// matches.
+func (q *Queue[T]) PopAll(pred func(T) bool) []T {
+	var removed []T
+	q.FilterInPlace(func(v T) bool {
+		if pred(v) {
+			removed = append(removed, v)
+			return false
+		}
+		return true
+	})
+	return removed
+}
@@ -1,8 +1,10 @@
 package bfq
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"unsafe"
 )
 
 func BenchmarkQueueCreation(b *testing.B) {
@@ -361,3 +363,2100 @@ func TestLargeQueueOperations(t *testing.T) {
 		t.Errorf("Expected queue to be empty, but it's not")
 	}
 }
+
+func TestFilterInPlace(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 100; i++ {
+		q.PushFront(i)
+	}
+
+	// Keep only the even elements
+	if kept := q.FilterInPlace(func(v int) bool { return v%2 == 0 }); kept != 50 {
+		t.Errorf("Expected 50 elements kept, got %d", kept)
+	}
+	if q.Len() != 50 {
+		t.Errorf("Expected queue length 50, got %d", q.Len())
+	}
+
+	// Order of the kept elements must be preserved
+	for i := 98; i >= 0; i -= 2 {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+
+	// Ensure the queue is empty
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestFilterInPlaceZeroesFreedSlots(t *testing.T) {
+	q := NewQueue[*Data]()
+	for i := 0; i < 4; i++ {
+		q.PushBack(&Data{ID: i})
+	}
+	q.FilterInPlace(func(d *Data) bool { return d.ID < 2 })
+
+	for i := q.Len(); i < len(q.buf); i++ {
+		if q.buf[(q.front+i)&(len(q.buf)-1)] != nil {
+			t.Errorf("Expected freed slot %d to be zeroed", i)
+		}
+	}
+}
+
+func TestPeekFrontAndPeekBack(t *testing.T) {
+	q := NewQueue[int]()
+	if _, ok := q.PeekFront(); ok {
+		t.Errorf("Expected PeekFront to return false on empty queue")
+	}
+	if _, ok := q.PeekBack(); ok {
+		t.Errorf("Expected PeekBack to return false on empty queue")
+	}
+
+	q.PushBack(10)
+	q.PushBack(20)
+	if front, ok := q.PeekFront(); !ok || front != 10 {
+		t.Errorf("Expected front element 10, got %v", front)
+	}
+	if back, ok := q.PeekBack(); !ok || back != 20 {
+		t.Errorf("Expected back element 20, got %v", back)
+	}
+
+	// Peeking must not remove anything
+	if q.Len() != 2 {
+		t.Errorf("Expected queue length 2, got %d", q.Len())
+	}
+}
+
+func TestInsertAtAll(t *testing.T) {
+	tests := []struct {
+		index int
+		want  []int
+	}{
+		{0, []int{7, 8, 9, 0, 1, 2, 3, 4, 5}},
+		{2, []int{0, 1, 7, 8, 9, 2, 3, 4, 5}},
+		{4, []int{0, 1, 2, 3, 7, 8, 9, 4, 5}},
+		{6, []int{0, 1, 2, 3, 4, 5, 7, 8, 9}},
+	}
+	for _, tt := range tests {
+		// Start from a wrapped-around buffer
+		q := NewQueue[int]()
+		for i := 5; i >= 0; i-- {
+			q.PushFront(i)
+		}
+		if !q.InsertAtAll(tt.index, 7, 8, 9) {
+			t.Errorf("Expected InsertAtAll(%d) to succeed", tt.index)
+		}
+		if q.Len() != len(tt.want) {
+			t.Errorf("Expected queue length %d, got %d", len(tt.want), q.Len())
+		}
+		for _, want := range tt.want {
+			if val, ok := q.PopFront(); !ok || val != want {
+				t.Errorf("InsertAtAll(%d): expected popped value %d, got %v", tt.index, want, val)
+			}
+		}
+	}
+}
+
+func TestInsertAtAllOutOfRange(t *testing.T) {
+	q := NewQueue[int]()
+	q.PushBack(1)
+	if q.InsertAtAll(-1, 2) {
+		t.Errorf("Expected InsertAtAll(-1) to fail")
+	}
+	if q.InsertAtAll(2, 2) {
+		t.Errorf("Expected InsertAtAll(2) to fail on queue of length 1")
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected queue length 1, got %d", q.Len())
+	}
+}
+
+func TestInsertAtAllLargeBatch(t *testing.T) {
+	q := NewQueue[int]()
+	q.PushBack(0)
+	q.PushBack(101)
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i + 1
+	}
+	q.InsertAtAll(1, items...)
+	for i := 0; i <= 101; i++ {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+}
+
+func TestMoveToFrontAndMoveToBack(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 4; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	// [0 1 2 3 4] -> [3 0 1 2 4]
+	if !q.MoveToFront(3) {
+		t.Errorf("Expected MoveToFront(3) to succeed")
+	}
+	if s := q.String(); s != "[3 0 1 2 4]" {
+		t.Errorf("Expected [3 0 1 2 4], got %s", s)
+	}
+
+	// [3 0 1 2 4] -> [3 1 2 4 0]
+	if !q.MoveToBack(1) {
+		t.Errorf("Expected MoveToBack(1) to succeed")
+	}
+	if s := q.String(); s != "[3 1 2 4 0]" {
+		t.Errorf("Expected [3 1 2 4 0], got %s", s)
+	}
+
+	// Out-of-range indices are rejected
+	if q.MoveToFront(5) || q.MoveToBack(-1) {
+		t.Errorf("Expected out-of-range moves to fail")
+	}
+	if q.Len() != 5 {
+		t.Errorf("Expected queue length 5, got %d", q.Len())
+	}
+}
+
+func TestNewQueueWithCapacity(t *testing.T) {
+	tests := []struct {
+		capacity int
+		want     int
+	}{
+		{0, minCapacity},
+		{5, minCapacity},
+		{8, 8},
+		{9, 16},
+		{1000, 1024},
+	}
+	for _, tt := range tests {
+		q, err := NewQueueWithCapacity[int](tt.capacity)
+		if err != nil {
+			t.Fatalf("Unexpected error for capacity %d: %v", tt.capacity, err)
+		}
+		if len(q.buf) != tt.want {
+			t.Errorf("Expected buffer size %d for capacity %d, got %d", tt.want, tt.capacity, len(q.buf))
+		}
+		if !q.IsEmpty() {
+			t.Errorf("Expected queue to be empty, but it's not")
+		}
+	}
+}
+
+func TestNewQueueWithCapacityErrors(t *testing.T) {
+	if _, err := NewQueueWithCapacity[int](-1); !errors.Is(err, ErrNegativeCapacity) {
+		t.Errorf("Expected ErrNegativeCapacity, got %v", err)
+	}
+	if _, err := NewQueueWithCapacity[int](maxCapacity + 1); !errors.Is(err, ErrCapacityTooLarge) {
+		t.Errorf("Expected ErrCapacityTooLarge, got %v", err)
+	}
+}
+
+func TestSetCapacity(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+
+	// Growing keeps the elements in order
+	if !q.SetCapacity(100) {
+		t.Errorf("Expected SetCapacity(100) to succeed")
+	}
+	if len(q.buf) != 128 {
+		t.Errorf("Expected buffer size 128, got %d", len(q.buf))
+	}
+
+	// Shrinking below the current length is rejected
+	if q.SetCapacity(9) {
+		t.Errorf("Expected SetCapacity(9) to fail with 10 elements")
+	}
+	if len(q.buf) != 128 {
+		t.Errorf("Expected buffer size to stay 128, got %d", len(q.buf))
+	}
+
+	// Shrinking to fit succeeds
+	if !q.SetCapacity(10) {
+		t.Errorf("Expected SetCapacity(10) to succeed")
+	}
+	if len(q.buf) != 16 {
+		t.Errorf("Expected buffer size 16, got %d", len(q.buf))
+	}
+	for i := 9; i >= 0; i-- {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	q := NewQueue[int]()
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected error on new queue: %v", err)
+	}
+
+	// Exercise wraparound, growth and shrinking
+	for i := 0; i < 100; i++ {
+		q.PushFront(i)
+		q.PushBack(i)
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Unexpected error after push %d: %v", i, err)
+		}
+	}
+	for !q.IsEmpty() {
+		q.PopBack()
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Unexpected error after pop: %v", err)
+		}
+	}
+}
+
+func TestValidateDetectsCorruption(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(q *Queue[int])
+	}{
+		{"capacity", func(q *Queue[int]) { q.buf = make([]int, 4) }},
+		{"length", func(q *Queue[int]) { q.length = -1 }},
+		{"front", func(q *Queue[int]) { q.front = len(q.buf) }},
+		{"back", func(q *Queue[int]) { q.back = -1 }},
+		{"distance", func(q *Queue[int]) { q.length++ }},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		q.PushBack(1)
+		q.PushBack(2)
+		tt.mutate(q)
+		if err := q.Validate(); err == nil {
+			t.Errorf("Expected Validate to detect corrupted %s", tt.name)
+		}
+	}
+}
+
+func TestEnds(t *testing.T) {
+	q := NewQueue[int]()
+	if _, _, ok := q.Ends(); ok {
+		t.Errorf("Expected Ends to return false on empty queue")
+	}
+
+	q.PushBack(10)
+	if front, back, ok := q.Ends(); !ok || front != 10 || back != 10 {
+		t.Errorf("Expected ends (10, 10), got (%v, %v)", front, back)
+	}
+
+	q.PushFront(20)
+	q.PushBack(30)
+	if front, back, ok := q.Ends(); !ok || front != 20 || back != 30 {
+		t.Errorf("Expected ends (20, 30), got (%v, %v)", front, back)
+	}
+}
+
+func TestIsPalindrome(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	tests := []struct {
+		items []int
+		want  bool
+	}{
+		{nil, true},
+		{[]int{1}, true},
+		{[]int{1, 1}, true},
+		{[]int{1, 2}, false},
+		{[]int{1, 2, 1}, true},
+		{[]int{1, 2, 3, 2, 1}, true},
+		{[]int{1, 2, 3, 3, 1}, false},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := len(tt.items) - 1; i >= 0; i-- {
+			q.PushFront(tt.items[i])
+		}
+		if got := q.IsPalindrome(eq); got != tt.want {
+			t.Errorf("IsPalindrome(%v): expected %v, got %v", tt.items, tt.want, got)
+		}
+	}
+}
+
+func TestScanPairs(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushBack(i)
+	}
+
+	var pairs [][2]int
+	q.ScanPairs(func(left, right int) bool {
+		pairs = append(pairs, [2]int{left, right})
+		return true
+	})
+	if len(pairs) != 2 || pairs[0] != [2]int{0, 4} || pairs[1] != [2]int{1, 3} {
+		t.Errorf("Expected pairs [[0 4] [1 3]], got %v", pairs)
+	}
+
+	// Returning false stops the scan
+	calls := 0
+	q.ScanPairs(func(left, right int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected scan to stop after 1 call, got %d", calls)
+	}
+}
+
+func TestPushBackLen(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 1; i <= 20; i++ {
+		if n := q.PushBackLen(i); n != i {
+			t.Errorf("Expected length %d after push, got %d", i, n)
+		}
+	}
+	if back, ok := q.Back(); !ok || back != 20 {
+		t.Errorf("Expected back element 20, got %v", back)
+	}
+}
+
+func TestFromMapKeysAndValues(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8, "i": 9}
+
+	keys := FromMapKeys(m)
+	if keys.Len() != len(m) {
+		t.Errorf("Expected %d keys, got %d", len(m), keys.Len())
+	}
+	seen := make(map[string]bool)
+	for !keys.IsEmpty() {
+		k, _ := keys.PopFront()
+		if _, ok := m[k]; !ok || seen[k] {
+			t.Errorf("Unexpected key %q", k)
+		}
+		seen[k] = true
+	}
+
+	values := FromMapValues(m)
+	if values.Len() != len(m) {
+		t.Errorf("Expected %d values, got %d", len(m), values.Len())
+	}
+	sum := 0
+	for !values.IsEmpty() {
+		v, _ := values.PopFront()
+		sum += v
+	}
+	if sum != 45 {
+		t.Errorf("Expected values to sum to 45, got %d", sum)
+	}
+}
+
+func TestFromMapKeysEmpty(t *testing.T) {
+	if q := FromMapKeys(map[int]bool{}); !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestSetCapacityExactFit(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 16; i++ {
+		q.PushBack(i)
+	}
+
+	// A full buffer must keep its back index in range
+	if !q.SetCapacity(16) {
+		t.Errorf("Expected SetCapacity(16) to succeed")
+	}
+	q.PopFront()
+	q.PushBack(16)
+	for i := 1; i <= 16; i++ {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+}
+
+func TestTee(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+
+	a, b := q.Tee()
+
+	// Draining one copy must not affect the other or the source
+	for i := 9; i >= 0; i-- {
+		if val, ok := a.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+	if b.Len() != 10 || q.Len() != 10 {
+		t.Errorf("Expected other copy and source length 10, got %d and %d", b.Len(), q.Len())
+	}
+	for i := 0; i < 10; i++ {
+		if val, ok := b.PopBack(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+	if s := q.String(); s != "[9 8 7 6 5 4 3 2 1 0]" {
+		t.Errorf("Expected source to be intact, got %s", s)
+	}
+}
+
+func TestRetainFirstAndRetainLast(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 99; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	// n >= Len() is a no-op
+	q.RetainLast(100)
+	q.RetainFirst(200)
+	if q.Len() != 100 {
+		t.Errorf("Expected queue length 100, got %d", q.Len())
+	}
+
+	q.RetainLast(30)
+	if front, back, _ := q.Ends(); q.Len() != 30 || front != 70 || back != 99 {
+		t.Errorf("Expected [70..99], got length %d with ends (%v, %v)", q.Len(), front, back)
+	}
+
+	q.RetainFirst(10)
+	if front, back, _ := q.Ends(); q.Len() != 10 || front != 70 || back != 79 {
+		t.Errorf("Expected [70..79], got length %d with ends (%v, %v)", q.Len(), front, back)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+
+	q.RetainFirst(0)
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestRetainZeroesFreedSlots(t *testing.T) {
+	q := NewQueue[*Data]()
+	for i := 0; i < 6; i++ {
+		q.PushBack(&Data{ID: i})
+	}
+	q.RetainLast(4)
+	q.RetainFirst(2)
+	for i := q.Len(); i < len(q.buf); i++ {
+		if q.buf[(q.front+i)&(len(q.buf)-1)] != nil {
+			t.Errorf("Expected freed slot %d to be zeroed", i)
+		}
+	}
+}
+
+func TestPopFrontWhile(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 99; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	items := q.PopFrontWhile(func(v int) bool { return v < 60 })
+	if len(items) != 60 {
+		t.Fatalf("Expected 60 popped elements, got %d", len(items))
+	}
+	for i, v := range items {
+		if v != i {
+			t.Errorf("Expected popped value %d, got %v", i, v)
+		}
+	}
+
+	// The first non-matching element stays at the front
+	if front, ok := q.Front(); !ok || front != 60 || q.Len() != 40 {
+		t.Errorf("Expected front 60 with length 40, got %v with length %d", front, q.Len())
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+
+	// Nothing matches
+	if items := q.PopFrontWhile(func(v int) bool { return v < 0 }); len(items) != 0 {
+		t.Errorf("Expected no popped elements, got %v", items)
+	}
+
+	// Everything matches
+	q.PopFrontWhile(func(int) bool { return true })
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestPopFrontUntilExpiry(t *testing.T) {
+	type entry struct {
+		deadline int64
+		payload  *Data
+	}
+	q := NewQueue[entry]()
+	for i := int64(0); i < 1000; i++ {
+		q.PushBack(entry{deadline: i, payload: &Data{ID: int(i)}})
+	}
+
+	// Drain everything that expired before now
+	now := int64(990)
+	expired := q.PopFrontUntil(func(e entry) bool { return e.deadline < now })
+	if len(expired) != 990 {
+		t.Errorf("Expected 990 expired entries, got %d", len(expired))
+	}
+	if front, ok := q.Front(); !ok || front.deadline != now {
+		t.Errorf("Expected front deadline %d, got %v", now, front.deadline)
+	}
+
+	// The buffer is shrunk and freed slots hold no references
+	if len(q.buf) != 32 {
+		t.Errorf("Expected buffer size 32 after shrinking, got %d", len(q.buf))
+	}
+	for i := q.Len(); i < len(q.buf); i++ {
+		if q.buf[(q.front+i)&(len(q.buf)-1)].payload != nil {
+			t.Errorf("Expected freed slot %d to be zeroed", i)
+		}
+	}
+}
+
+func TestCopyIntoNonPowerOfTwo(t *testing.T) {
+	// Grow dst to 12 slots with a 1.5 growth factor
+	dst := NewQueue[int]()
+	dst.SetGrowthFactor(1.5)
+	for i := 0; i < 9; i++ {
+		dst.PushBack(i)
+	}
+	if len(dst.buf) != 12 {
+		t.Fatalf("Expected dst capacity 12, got %d", len(dst.buf))
+	}
+
+	Of(1, 2, 3, 4, 5).CopyInto(dst)
+	if err := dst.Validate(); err != nil {
+		t.Fatalf("dst is invalid after CopyInto: %v", err)
+	}
+	dst.PushBack(6)
+	if !EqualSlice(dst, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("Expected [1 2 3 4 5 6], got %v", dst)
+	}
+	if err := dst.Validate(); err != nil {
+		t.Errorf("dst is invalid after PushBack: %v", err)
+	}
+}
+
+func TestCopyInto(t *testing.T) {
+	src := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		src.PushFront(i)
+	}
+
+	// A larger destination keeps its buffer
+	dst := NewQueue[int]()
+	dst.SetCapacity(64)
+	for i := 0; i < 40; i++ {
+		dst.PushBack(-1)
+	}
+	buf := dst.buf
+	src.CopyInto(dst)
+	if &dst.buf[0] != &buf[0] {
+		t.Errorf("Expected destination buffer to be reused")
+	}
+	if dst.String() != src.String() {
+		t.Errorf("Expected %s, got %s", src.String(), dst.String())
+	}
+	for i := dst.Len(); i < len(dst.buf); i++ {
+		if dst.buf[i] != 0 {
+			t.Errorf("Expected unused slot %d to be zeroed, got %v", i, dst.buf[i])
+		}
+	}
+	if err := dst.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+
+	// A smaller destination grows to fit
+	small := NewQueue[int]()
+	src.CopyInto(small)
+	if small.String() != src.String() {
+		t.Errorf("Expected %s, got %s", src.String(), small.String())
+	}
+
+	// The copies are independent
+	small.PopFront()
+	if src.Len() != 10 {
+		t.Errorf("Expected source length 10, got %d", src.Len())
+	}
+}
+
+func TestFromSlicePowerOfTwo(t *testing.T) {
+	// A slice that exactly fills the buffer must leave back in range
+	q := FromSlice([]int{0, 1, 2, 3, 4, 5, 6, 7})
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+	q.PopFront()
+	q.PushBack(8)
+	for i := 1; i <= 8; i++ {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+}
+
+func TestFromSliceReversed(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 8, 16, 100} {
+		slice := make([]int, n)
+		for i := range slice {
+			slice[i] = i
+		}
+		q := FromSliceReversed(slice)
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Unexpected invalid state for n=%d: %v", n, err)
+		}
+		if q.Len() != n {
+			t.Errorf("Expected queue length %d, got %d", n, q.Len())
+		}
+
+		// slice[0] ends up at the back
+		for i := n - 1; i >= 0; i-- {
+			if val, ok := q.PopFront(); !ok || val != i {
+				t.Errorf("Expected popped value %d, got %v", i, val)
+			}
+		}
+	}
+}
+
+func TestSizeInBytes(t *testing.T) {
+	q := NewQueue[int64]()
+	overhead := unsafe.Sizeof(*q)
+	if got := q.SizeInBytes(); got != 8*8+overhead {
+		t.Errorf("Expected %d bytes, got %d", 8*8+overhead, got)
+	}
+
+	// Growing the buffer increases the estimate
+	for i := 0; i < 9; i++ {
+		q.PushBack(int64(i))
+	}
+	if got := q.SizeInBytes(); got != 16*8+overhead {
+		t.Errorf("Expected %d bytes, got %d", 16*8+overhead, got)
+	}
+
+	// Elements are counted by their own size only
+	s := NewQueue[Data]()
+	if got := s.SizeInBytes(); got != 8*unsafe.Sizeof(Data{})+unsafe.Sizeof(*s) {
+		t.Errorf("Unexpected struct queue size %d", got)
+	}
+}
+
+func TestHeadTail(t *testing.T) {
+	q := NewQueue[int]()
+	if _, tail, ok := q.HeadTail(); ok || tail != nil {
+		t.Errorf("Expected HeadTail to return false on empty queue")
+	}
+
+	for i := 2; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	// Recursively consume without touching the source
+	for want := 0; want < 3; want++ {
+		head, tail, ok := q.HeadTail()
+		if !ok || head != want || tail.Len() != 2-want {
+			t.Errorf("Expected head %d with tail length %d, got %v with %d", want, 2-want, head, tail.Len())
+		}
+		if want == 0 && q.Len() != 3 {
+			t.Errorf("Expected source length 3, got %d", q.Len())
+		}
+		q = tail
+	}
+	if !q.IsEmpty() {
+		t.Errorf("Expected final tail to be empty, but it's not")
+	}
+}
+
+func TestPopOrDefault(t *testing.T) {
+	q := NewQueue[int]()
+	if v := q.PopFrontOrDefault(-1); v != -1 {
+		t.Errorf("Expected default -1, got %v", v)
+	}
+	if v := q.PopBackOrDefault(-2); v != -2 {
+		t.Errorf("Expected default -2, got %v", v)
+	}
+
+	q.PushBack(10)
+	q.PushBack(20)
+	if v := q.PopFrontOrDefault(-1); v != 10 {
+		t.Errorf("Expected popped value 10, got %v", v)
+	}
+	if v := q.PopBackOrDefault(-1); v != 20 {
+		t.Errorf("Expected popped value 20, got %v", v)
+	}
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestFrontOrAndBackOr(t *testing.T) {
+	q := NewQueue[string]()
+	if v := q.FrontOr("none"); v != "none" {
+		t.Errorf("Expected default \"none\", got %q", v)
+	}
+	if v := q.BackOr("none"); v != "none" {
+		t.Errorf("Expected default \"none\", got %q", v)
+	}
+
+	q.PushBack("a")
+	q.PushBack("b")
+	if v := q.FrontOr("none"); v != "a" {
+		t.Errorf("Expected front \"a\", got %q", v)
+	}
+	if v := q.BackOr("none"); v != "b" {
+		t.Errorf("Expected back \"b\", got %q", v)
+	}
+	if q.Len() != 2 {
+		t.Errorf("Expected queue length 2, got %d", q.Len())
+	}
+}
+
+func TestReplaceFunc(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 6; i++ {
+		q.PushFront(i)
+		q.PushBack(i)
+	}
+
+	q.ReplaceFunc(func(v int) int { return v * 10 })
+	if s := q.String(); s != "[50 40 30 20 10 0 0 10 20 30 40 50]" {
+		t.Errorf("Expected [50 40 30 20 10 0 0 10 20 30 40 50], got %s", s)
+	}
+}
+
+func TestPushBackResized(t *testing.T) {
+	q := NewQueue[int]()
+	var resizes []int
+	for i := 0; i < 100; i++ {
+		if q.PushBackResized(i) {
+			resizes = append(resizes, i)
+		}
+	}
+
+	// The buffer doubles from 8 once it is full
+	want := []int{8, 16, 32, 64}
+	if len(resizes) != len(want) {
+		t.Fatalf("Expected resizes at %v, got %v", want, resizes)
+	}
+	for i := range want {
+		if resizes[i] != want[i] {
+			t.Errorf("Expected resize at push %d, got %d", want[i], resizes[i])
+		}
+	}
+}
+
+func TestForEachChunk(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 9; i >= 0; i-- {
+		q.PushFront(i)
+	}
+
+	var chunks []string
+	q.ForEachChunk(4, func(chunk []int) {
+		chunks = append(chunks, fmt.Sprint(chunk))
+	})
+	want := []string{"[0 1 2 3]", "[4 5 6 7]", "[8 9]"}
+	if fmt.Sprint(chunks) != fmt.Sprint(want) {
+		t.Errorf("Expected chunks %v, got %v", want, chunks)
+	}
+
+	// Invalid sizes and empty queues never call fn
+	NewQueue[int]().ForEachChunk(4, func([]int) { t.Errorf("Unexpected call on empty queue") })
+	q.ForEachChunk(0, func([]int) { t.Errorf("Unexpected call with size 0") })
+}
+
+func TestForEachChunkAllocs(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 1000; i++ {
+		q.PushBack(i)
+	}
+	sum := 0
+	allocs := testing.AllocsPerRun(10, func() {
+		q.ForEachChunk(64, func(chunk []int) {
+			for _, v := range chunk {
+				sum += v
+			}
+		})
+	})
+	if allocs > 1 {
+		t.Errorf("Expected a single scratch allocation, got %v", allocs)
+	}
+}
+
+func TestIndexHelpers(t *testing.T) {
+	q := NewQueue[int]()
+	if q.BackIndex() != -1 || q.ValidIndex(0) {
+		t.Errorf("Expected no valid indices on empty queue")
+	}
+
+	for i := 0; i < 5; i++ {
+		q.PushFront(i)
+	}
+	if q.FrontIndex() != 0 {
+		t.Errorf("Expected front index 0, got %d", q.FrontIndex())
+	}
+	if q.BackIndex() != 4 {
+		t.Errorf("Expected back index 4, got %d", q.BackIndex())
+	}
+	for i, want := range map[int]bool{-1: false, 0: true, 4: true, 5: false} {
+		if got := q.ValidIndex(i); got != want {
+			t.Errorf("ValidIndex(%d): expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestSplitFunc(t *testing.T) {
+	q := NewQueue[byte]()
+	for _, b := range []byte("key=value") {
+		q.PushBack(b)
+	}
+
+	before, after, found := q.SplitFunc(func(b byte) bool { return b == '=' })
+	if !found {
+		t.Fatalf("Expected delimiter to be found")
+	}
+	if before.Len() != 3 || after.Len() != 6 {
+		t.Errorf("Expected lengths 3 and 6, got %d and %d", before.Len(), after.Len())
+	}
+	if front, _ := after.Front(); front != '=' {
+		t.Errorf("Expected after to start with the match, got %q", front)
+	}
+
+	// The results are independent of the source
+	before.PopFront()
+	if q.Len() != 9 {
+		t.Errorf("Expected source length 9, got %d", q.Len())
+	}
+}
+
+func TestSplitFuncNotFound(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+
+	before, after, found := q.SplitFunc(func(v int) bool { return v > 100 })
+	if found {
+		t.Errorf("Expected no match")
+	}
+	if before.String() != q.String() || !after.IsEmpty() {
+		t.Errorf("Expected everything before, got %s and %s", before, after)
+	}
+	if err := before.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+}
+
+func TestFindLast(t *testing.T) {
+	q := NewQueue[Data]()
+	for i := 0; i < 10; i++ {
+		q.PushBack(Data{ID: i, Name: fmt.Sprintf("event-%d", i%3)})
+	}
+
+	index, value, ok := q.FindLast(func(d Data) bool { return d.Name == "event-1" })
+	if !ok || index != 7 || value.ID != 7 {
+		t.Errorf("Expected match at index 7, got %d with ID %d", index, value.ID)
+	}
+
+	index, _, ok = q.FindLast(func(d Data) bool { return d.Name == "missing" })
+	if ok || index != -1 {
+		t.Errorf("Expected no match, got index %d", index)
+	}
+}
+
+func TestSteal(t *testing.T) {
+	tests := []struct {
+		name string
+		fill func(q *Queue[int])
+	}{
+		{"contiguous", func(q *Queue[int]) {
+			for i := 0; i < 12; i++ {
+				q.PushBack(i - 2)
+			}
+			q.PopFront()
+			q.PopFront()
+		}},
+		{"wrapped", func(q *Queue[int]) {
+			for i := 9; i >= 0; i-- {
+				q.PushFront(i)
+			}
+		}},
+		{"split", func(q *Queue[int]) {
+			for i := 4; i < 10; i++ {
+				q.PushBack(i)
+			}
+			for i := 3; i >= 0; i-- {
+				q.PushFront(i)
+			}
+			if q.front+q.length <= len(q.buf) {
+				t.Fatal("Expected the elements to wrap around the end of the buffer")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		tt.fill(q)
+		buf := q.buf
+
+		items := q.Steal()
+		if len(items) != 10 {
+			t.Fatalf("%s: expected 10 items, got %d", tt.name, len(items))
+		}
+		for i, v := range items {
+			if v != i {
+				t.Errorf("%s: expected item %d, got %v", tt.name, i, v)
+			}
+		}
+		if !q.IsEmpty() || len(q.buf) != minCapacity {
+			t.Errorf("%s: expected queue reset to an empty minimum buffer", tt.name)
+		}
+
+		// The queue no longer aliases what it handed out
+		q.PushBack(100)
+		if items[0] != 0 || &q.buf[0] == &buf[0] {
+			t.Errorf("%s: expected stolen slice to be independent of the queue", tt.name)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	q := NewQueue[Data]()
+	for i := 0; i < 5; i++ {
+		q.PushFront(Data{ID: i, Name: "item"})
+		q.PushBack(Data{ID: i + 10, Name: "item"})
+	}
+
+	q.Apply(func(d *Data) { d.ID++ })
+	q.Apply(func(d *Data) { d.Name += "!" })
+
+	want := []int{5, 4, 3, 2, 1, 11, 12, 13, 14, 15}
+	for _, id := range want {
+		if val, ok := q.PopFront(); !ok || val.ID != id || val.Name != "item!" {
+			t.Errorf("Expected {%d item!}, got %v", id, val)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	q := Of(1, 2, 3)
+	if s := q.String(); s != "[1 2 3]" {
+		t.Errorf("Expected [1 2 3], got %s", s)
+	}
+	if Of[int]().Len() != 0 {
+		t.Errorf("Expected empty queue from no items")
+	}
+
+	// A full power-of-two batch must stay valid and not alias the caller
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	q = Of(items...)
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+	items[0] = 100
+	if front, _ := q.Front(); front != 0 {
+		t.Errorf("Expected queue to be independent of the argument slice, got front %v", front)
+	}
+	q.PopFront()
+	q.PushBack(8)
+	if s := q.String(); s != "[1 2 3 4 5 6 7 8]" {
+		t.Errorf("Expected [1 2 3 4 5 6 7 8], got %s", s)
+	}
+}
+
+func TestPopEnds(t *testing.T) {
+	tests := []struct {
+		length, n     int
+		fronts, backs string
+		rest          string
+	}{
+		{10, 3, "[0 1 2]", "[9 8 7]", "[3 4 5 6]"},
+		{6, 3, "[0 1 2]", "[5 4 3]", "[]"},
+		{5, 3, "[0 1 2]", "[4 3]", "[]"},
+		{2, 3, "[0 1]", "[]", "[]"},
+		{4, 0, "[]", "[]", "[0 1 2 3]"},
+		{0, 2, "[]", "[]", "[]"},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := tt.length - 1; i >= 0; i-- {
+			q.PushFront(i)
+		}
+		fronts, backs := q.PopEnds(tt.n)
+		if fmt.Sprint(fronts) != tt.fronts || fmt.Sprint(backs) != tt.backs || q.String() != tt.rest {
+			t.Errorf("PopEnds(%d) on %d elements: expected %s %s %s, got %v %v %s",
+				tt.n, tt.length, tt.fronts, tt.backs, tt.rest, fronts, backs, q)
+		}
+		if err := q.Validate(); err != nil {
+			t.Errorf("Unexpected invalid state: %v", err)
+		}
+	}
+}
+
+func TestSetGrowthFactor(t *testing.T) {
+	tests := []struct {
+		factor float64
+		sizes  []int
+	}{
+		{0, []int{16, 32, 64}},
+		{1.5, []int{12, 18, 27}},
+		{2, []int{16, 32, 64}},
+		{3, []int{24, 72, 216}},
+		{4, []int{32, 128, 512}},
+		{8, []int{64, 512, 4096}},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		q.SetGrowthFactor(tt.factor)
+		var sizes []int
+		for i := 0; len(sizes) < len(tt.sizes); i++ {
+			if q.PushBackResized(i) {
+				sizes = append(sizes, len(q.buf))
+			}
+		}
+		if fmt.Sprint(sizes) != fmt.Sprint(tt.sizes) {
+			t.Errorf("Factor %v: expected sizes %v, got %v", tt.factor, tt.sizes, sizes)
+		}
+		if err := q.Validate(); err != nil {
+			t.Errorf("Unexpected invalid state: %v", err)
+		}
+	}
+}
+
+func TestGrowthFactorWraparound(t *testing.T) {
+	// Buffer sizes that are not powers of two must wrap correctly at both
+	// ends and through the bulk operations.
+	q := NewQueue[int]()
+	q.SetGrowthFactor(1.5)
+	var model []int
+	for i := 0; i < 200; i++ {
+		switch i % 5 {
+		case 0, 1:
+			q.PushBack(i)
+			model = append(model, i)
+		case 2:
+			q.PushFront(i)
+			model = append([]int{i}, model...)
+		case 3:
+			q.InsertAtAll(len(model)/3, i, -i)
+			model = append(model[:len(model)/3], append([]int{i, -i}, model[len(model)/3:]...)...)
+		case 4:
+			q.PopFront()
+			model = model[1:]
+			q.Next()
+			model = append(model[1:], model[0])
+		}
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Step %d: %v", i, err)
+		}
+	}
+	if len(q.buf)&(len(q.buf)-1) == 0 {
+		t.Errorf("Expected a non-power-of-two capacity, got %d", len(q.buf))
+	}
+	if !EqualSlice(q, model) {
+		t.Fatalf("Expected %v, got %v", model, q)
+	}
+	if got := q.ToReversedSlice(); got[0] != model[len(model)-1] {
+		t.Errorf("Expected reversed slice to start with %d, got %d", model[len(model)-1], got[0])
+	}
+	for len(model) > 0 {
+		v, _ := q.PopBack()
+		if v != model[len(model)-1] {
+			t.Fatalf("Expected %d from PopBack, got %d", model[len(model)-1], v)
+		}
+		model = model[:len(model)-1]
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Unexpected invalid state while shrinking: %v", err)
+		}
+	}
+}
+
+func BenchmarkQueueGrowthFactor(b *testing.B) {
+	for _, factor := range []float64{1.5, 2, 4, 8} {
+		b.Run(fmt.Sprintf("factor=%v", factor), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				q := NewQueue[int]()
+				q.SetGrowthFactor(factor)
+				for j := 0; j < 100000; j++ {
+					q.PushBack(j)
+				}
+			}
+		})
+	}
+}
+
+func TestGrowOverflowGuard(t *testing.T) {
+	defer func(old int) { maxSize = old }(maxSize)
+	maxSize = 32
+
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if r := recover(); r != "bfq: queue too large" {
+				t.Errorf("%s: expected \"queue too large\" panic, got %v", name, r)
+			}
+		}()
+		fn()
+	}
+
+	// Growth stops at the maximum size, then pushing further panics
+	q := NewQueue[int]()
+	q.SetGrowthFactor(8)
+	for i := 0; i < 32; i++ {
+		q.PushBack(i)
+	}
+	if len(q.buf) != 32 {
+		t.Errorf("Expected buffer capped at 32, got %d", len(q.buf))
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+	expectPanic("PushBack", func() { q.PushBack(32) })
+	expectPanic("PushFront", func() { q.PushFront(-1) })
+	if q.Len() != 32 {
+		t.Errorf("Expected queue length 32 after failed pushes, got %d", q.Len())
+	}
+
+	// Bulk inserts are checked before anything is copied
+	expectPanic("InsertAtAll", func() { NewQueue[int]().InsertAtAll(0, make([]int, 33)...) })
+}
+
+func TestPushBackBounded(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		want := i < 8
+		if got := q.PushBackBounded(i, 8); got != want {
+			t.Errorf("PushBackBounded(%d): expected %v, got %v", i, want, got)
+		}
+	}
+
+	// Rejected pushes neither add elements nor grow the buffer
+	if q.Len() != 8 || len(q.buf) != 8 {
+		t.Errorf("Expected length 8 in a buffer of 8, got %d in %d", q.Len(), len(q.buf))
+	}
+	if back, _ := q.Back(); back != 7 {
+		t.Errorf("Expected back element 7, got %v", back)
+	}
+	if q.PushBackBounded(0, 0) {
+		t.Errorf("Expected PushBackBounded with maxLen 0 to fail")
+	}
+}
+
+func TestLastPushed(t *testing.T) {
+	q := NewQueue[int]()
+	if _, ok := q.LastPushed(); ok {
+		t.Errorf("Expected LastPushed to return false on empty queue")
+	}
+
+	steps := []struct {
+		front bool
+		v     int
+	}{
+		{false, 1}, {true, 2}, {true, 3}, {false, 4}, {true, 5},
+	}
+	for _, s := range steps {
+		if s.front {
+			q.PushFront(s.v)
+		} else {
+			q.PushBack(s.v)
+		}
+		if v, ok := q.LastPushed(); !ok || v != s.v {
+			t.Errorf("Expected last pushed %d, got %v", s.v, v)
+		}
+	}
+
+	// The tracked end survives growth
+	for i := 0; i < 20; i++ {
+		q.PushBack(i)
+	}
+	if v, _ := q.LastPushed(); v != 19 {
+		t.Errorf("Expected last pushed 19, got %v", v)
+	}
+
+	// Bulk insertions at an end are tracked; middle insertions are not
+	q.InsertAtAll(0, -1, -2)
+	if v, _ := q.LastPushed(); v != -1 {
+		t.Errorf("Expected last pushed -1 after inserting at the front, got %v", v)
+	}
+	q.InsertAtAll(5, 100)
+	if v, _ := q.LastPushed(); v != -1 {
+		t.Errorf("Expected a middle insertion to keep the front tracked, got %v", v)
+	}
+	q.PushBackSlice([]int{7, 8}, 0, 2)
+	if v, _ := q.LastPushed(); v != 8 {
+		t.Errorf("Expected last pushed 8 after PushBackSlice, got %v", v)
+	}
+	q.InsertAtAll(q.Len(), 9)
+	if v, _ := q.LastPushed(); v != 9 {
+		t.Errorf("Expected last pushed 9 after inserting at the back, got %v", v)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	q := NewQueue[int]()
+	last := q.Version()
+	changed := func(name string) {
+		t.Helper()
+		if v := q.Version(); v == last {
+			t.Errorf("Expected %s to change the version", name)
+		} else {
+			last = v
+		}
+	}
+	unchanged := func(name string) {
+		t.Helper()
+		if v := q.Version(); v != last {
+			t.Errorf("Expected %s to leave the version unchanged", name)
+			last = v
+		}
+	}
+
+	q.PushBack(1)
+	changed("PushBack")
+	q.PushFront(0)
+	changed("PushFront")
+	q.Front()
+	q.Back()
+	q.Len()
+	_ = q.String()
+	unchanged("read-only calls")
+	q.InsertAtAll(1, 5, 6, 7)
+	changed("InsertAtAll")
+	q.MoveToBack(0)
+	changed("MoveToBack")
+	q.ReplaceFunc(func(v int) int { return v })
+	changed("ReplaceFunc")
+	q.FilterInPlace(func(v int) bool { return v != 6 })
+	changed("FilterInPlace")
+	q.RetainLast(3)
+	changed("RetainLast")
+	q.PopFront()
+	changed("PopFront")
+	q.PopBack()
+	changed("PopBack")
+	q.PopFront()
+	changed("PopFront")
+	q.PopFront()
+	unchanged("PopFront on empty queue")
+}
+
+func TestPushBackSlice(t *testing.T) {
+	src := make([]int, 100)
+	for i := range src {
+		src[i] = i
+	}
+
+	// Start from a wrapped-around buffer
+	q := NewQueue[int]()
+	for i := 0; i < 6; i++ {
+		q.PushBack(-1)
+	}
+	for i := 0; i < 5; i++ {
+		q.PopFront()
+	}
+
+	if !q.PushBackSlice(src, 10, 15) {
+		t.Errorf("Expected PushBackSlice(10, 15) to succeed")
+	}
+	if !q.PushBackSlice(src, 20, 80) {
+		t.Errorf("Expected PushBackSlice(20, 80) to succeed")
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Unexpected invalid state: %v", err)
+	}
+	if q.Len() != 66 {
+		t.Errorf("Expected queue length 66, got %d", q.Len())
+	}
+	want := append([]int{-1}, append(src[10:15:15], src[20:80]...)...)
+	if !EqualSlice(q, want) {
+		t.Errorf("Expected %v, got %s", want, q)
+	}
+
+	for _, b := range [][2]int{{-1, 5}, {5, 4}, {0, 101}} {
+		if q.PushBackSlice(src, b[0], b[1]) {
+			t.Errorf("Expected PushBackSlice(%d, %d) to fail", b[0], b[1])
+		}
+	}
+	if q.Len() != 66 {
+		t.Errorf("Expected invalid bounds to leave the queue unchanged")
+	}
+}
+
+func TestSetContents(t *testing.T) {
+	q := NewQueue[*Data]()
+	for i := 0; i < 20; i++ {
+		q.PushFront(&Data{ID: i})
+	}
+	buf := q.buf
+
+	// Smaller contents reuse the buffer and clear the rest
+	q.SetContents([]*Data{{ID: 100}, {ID: 101}})
+	if &q.buf[0] != &buf[0] {
+		t.Errorf("Expected buffer to be reused")
+	}
+	if q.Len() != 2 {
+		t.Errorf("Expected queue length 2, got %d", q.Len())
+	}
+	if front, _ := q.Front(); front.ID != 100 {
+		t.Errorf("Expected front ID 100, got %d", front.ID)
+	}
+	for i := q.Len(); i < len(q.buf); i++ {
+		if q.buf[i] != nil {
+			t.Errorf("Expected leftover slot %d to be zeroed", i)
+		}
+	}
+
+	// Larger contents get a new buffer
+	big := make([]*Data, 64)
+	for i := range big {
+		big[i] = &Data{ID: i}
+	}
+	q.SetContents(big)
+	if q.Len() != 64 || len(q.buf) != 64 {
+		t.Errorf("Expected 64 elements in a buffer of 64, got %d in %d", q.Len(), len(q.buf))
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+
+	q.SetContents(nil)
+	if !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestTabulate(t *testing.T) {
+	for _, n := range []int{-1, 0, 5, 8, 16, 100} {
+		q := Tabulate(n, func(i int) int { return i * i })
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Unexpected invalid state for n=%d: %v", n, err)
+		}
+		if q.Len() != max(n, 0) {
+			t.Errorf("Expected queue length %d, got %d", max(n, 0), q.Len())
+		}
+		for i := 0; i < n; i++ {
+			if val, ok := q.PopFront(); !ok || val != i*i {
+				t.Errorf("Expected popped value %d, got %v", i*i, val)
+			}
+		}
+	}
+
+	// A buffer filled exactly to a power of two keeps working
+	q := Tabulate(8, func(i int) int { return i })
+	q.PushBack(8)
+	if back, _ := q.Back(); back != 8 || q.Len() != 9 {
+		t.Errorf("Expected back 8 with length 9, got %v with %d", back, q.Len())
+	}
+}
+
+func TestLongestRun(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	tests := []struct {
+		items         []int
+		start, length int
+	}{
+		{nil, 0, 0},
+		{[]int{7}, 0, 1},
+		{[]int{1, 2, 3}, 0, 1},
+		{[]int{1, 1, 2, 2, 2, 3}, 2, 3},
+		{[]int{1, 1, 2, 2}, 0, 2},
+		{[]int{1, 2, 3, 3, 3, 3}, 2, 4},
+	}
+	for _, tt := range tests {
+		q := NewQueue[int]()
+		for i := len(tt.items) - 1; i >= 0; i-- {
+			q.PushFront(tt.items[i])
+		}
+		start, length := q.LongestRun(eq)
+		if start != tt.start || length != tt.length {
+			t.Errorf("LongestRun(%v): expected (%d, %d), got (%d, %d)", tt.items, tt.start, tt.length, start, length)
+		}
+	}
+}
+
+func TestAtAndSet(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	for i := 0; i < 10; i++ {
+		if val, ok := q.At(i); !ok || val != 9-i {
+			t.Errorf("Expected At(%d) to be %d, got (%d, %v)", i, 9-i, val, ok)
+		}
+	}
+	for _, i := range []int{-1, 10} {
+		if _, ok := q.At(i); ok {
+			t.Errorf("Expected At(%d) to be out of range", i)
+		}
+		if q.Set(i, 0) {
+			t.Errorf("Expected Set(%d) to be out of range", i)
+		}
+	}
+
+	v := q.Version()
+	if !q.Set(2, 100) {
+		t.Errorf("Expected Set(2) to succeed")
+	}
+	if val, _ := q.At(2); val != 100 {
+		t.Errorf("Expected 100 at index 2, got %d", val)
+	}
+	if q.Version() == v {
+		t.Errorf("Expected Set to bump the version")
+	}
+}
+
+func TestAtFromBack(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+
+	// [9 8 ... 0]: the back is 0, one before it is 1, and so on
+	for n := 0; n < 10; n++ {
+		if val, ok := q.AtFromBack(n); !ok || val != n {
+			t.Errorf("AtFromBack(%d): expected %d, got %v", n, n, val)
+		}
+	}
+	for _, n := range []int{-1, 10} {
+		if _, ok := q.AtFromBack(n); ok {
+			t.Errorf("Expected AtFromBack(%d) to fail", n)
+		}
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	for _, start := range []int{0, 3, 6} {
+		// [9 8 ... 0] wraps around the end of the buffer
+		q := NewQueue[int]()
+		for i := 0; i < 10; i++ {
+			q.PushFront(i)
+		}
+		if !q.SetRange(start, []int{-1, -2, -3, -4}) {
+			t.Errorf("Expected SetRange(%d) to succeed", start)
+		}
+		for i := 0; i < 10; i++ {
+			want := 9 - i
+			if i >= start && i < start+4 {
+				want = -(i - start + 1)
+			}
+			if val, _ := q.PopFront(); val != want {
+				t.Errorf("SetRange(%d): expected element %d to be %d, got %v", start, i, want, val)
+			}
+		}
+	}
+
+	q := Of(1, 2, 3)
+	for _, start := range []int{-1, 2, 3} {
+		if q.SetRange(start, []int{0, 0}) {
+			t.Errorf("Expected SetRange(%d) with 2 values to fail on 3 elements", start)
+		}
+	}
+	if !q.SetRange(3, nil) || !EqualSlice(q, []int{1, 2, 3}) {
+		t.Errorf("Expected an empty range at the end to be a no-op")
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	for _, r := range [][2]int{{0, 10}, {2, 8}, {5, 5}, {0, 3}, {7, 10}} {
+		got, ok := q.GetRange(r[0], r[1])
+		if !ok || len(got) != r[1]-r[0] {
+			t.Errorf("GetRange(%d, %d): expected %d elements, got %v (%v)", r[0], r[1], r[1]-r[0], got, ok)
+			continue
+		}
+		for i, v := range got {
+			if want := 9 - (r[0] + i); v != want {
+				t.Errorf("GetRange(%d, %d): expected element %d to be %d, got %d", r[0], r[1], i, want, v)
+			}
+		}
+	}
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 11}} {
+		if got, ok := q.GetRange(r[0], r[1]); ok || got != nil {
+			t.Errorf("Expected GetRange(%d, %d) to fail, got %v", r[0], r[1], got)
+		}
+	}
+
+	// The result is a copy
+	got, _ := q.GetRange(0, 1)
+	got[0] = -1
+	if val, _ := q.PeekFront(); val != 9 {
+		t.Errorf("Expected GetRange to return a copy, front is now %d", val)
+	}
+}
+
+func TestSubQueue(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	sub := q.SubQueue(3, 9)
+	if err := sub.Validate(); err != nil {
+		t.Fatalf("SubQueue result is invalid: %v", err)
+	}
+	if !EqualSlice(sub, []int{6, 5, 4, 3, 2, 1}) {
+		t.Errorf("Expected [6 5 4 3 2 1], got %v", sub)
+	}
+
+	// The result is independent of the source
+	sub.PushBack(100)
+	sub.PopFront()
+	if q.Len() != 10 {
+		t.Errorf("Expected source length 10, got %d", q.Len())
+	}
+	if val, _ := q.PeekFront(); val != 9 {
+		t.Errorf("Expected source front 9, got %d", val)
+	}
+
+	if empty := q.SubQueue(4, 4); empty == nil || !empty.IsEmpty() {
+		t.Errorf("Expected an empty range to give an empty queue, got %v", empty)
+	}
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 11}} {
+		if got := q.SubQueue(r[0], r[1]); got != nil {
+			t.Errorf("Expected SubQueue(%d, %d) to return nil, got %v", r[0], r[1], got)
+		}
+	}
+}
+
+func TestLoadFactor(t *testing.T) {
+	q := NewQueue[int]()
+	if lf := q.LoadFactor(); lf != 0 {
+		t.Errorf("Expected load factor 0 for an empty queue, got %v", lf)
+	}
+	for i := 0; i < minCapacity; i++ {
+		q.PushBack(i)
+	}
+	if lf := q.LoadFactor(); lf != 1 {
+		t.Errorf("Expected load factor 1 for a full buffer, got %v", lf)
+	}
+	q.PushBack(minCapacity)
+	if lf, want := q.LoadFactor(), float64(minCapacity+1)/float64(2*minCapacity); lf != want {
+		t.Errorf("Expected load factor %v after growing, got %v", want, lf)
+	}
+}
+
+func TestPushBackEvict(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 3; i++ {
+		if _, ok := q.PushBackEvict(i, 3); ok {
+			t.Errorf("Expected no eviction while below the limit, pushing %d", i)
+		}
+	}
+	for i := 3; i < 20; i++ {
+		v, ok := q.PushBackEvict(i, 3)
+		if !ok || v != i-3 {
+			t.Errorf("Expected to evict %d, got (%d, %v)", i-3, v, ok)
+		}
+	}
+	if !EqualSlice(q, []int{17, 18, 19}) {
+		t.Errorf("Expected [17 18 19], got %v", q)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid: %v", err)
+	}
+
+	// A full buffer does not grow when the limit matches its capacity
+	full := NewQueue[int]()
+	for i := 0; i < 3*minCapacity; i++ {
+		full.PushBackEvict(i, minCapacity)
+	}
+	if got := len(full.buf); got != minCapacity {
+		t.Errorf("Expected the buffer to stay at %d, got %d", minCapacity, got)
+	}
+
+	// The vacated slot is zeroed
+	p := NewQueue[*Data]()
+	p.PushBack(&Data{1, "a"})
+	slot := p.front
+	p.PushBackEvict(&Data{2, "b"}, 1)
+	if p.buf[slot] != nil {
+		t.Errorf("Expected the evicted slot to be zeroed, got %v", p.buf[slot])
+	}
+
+	// A limit of 0 or less evicts v itself, whatever the queue holds
+	for _, q := range []*Queue[int]{NewQueue[int](), Of(1, 2)} {
+		n := q.Len()
+		for _, limit := range []int{0, -3} {
+			if v, ok := q.PushBackEvict(7, limit); !ok || v != 7 || q.Len() != n {
+				t.Errorf("Limit %d: expected to evict 7 and keep length %d, got (%d, %v) and %d", limit, n, v, ok, q.Len())
+			}
+		}
+	}
+
+	// A queue already over the limit evicts one element per push
+	over := Of(1, 2, 3, 4, 5)
+	if v, ok := over.PushBackEvict(6, 2); !ok || v != 1 {
+		t.Errorf("Expected to evict 1, got (%d, %v)", v, ok)
+	}
+	if !EqualSlice(over, []int{2, 3, 4, 5, 6}) {
+		t.Errorf("Expected [2 3 4 5 6], got %v", over)
+	}
+}
+
+func TestInsertByKey(t *testing.T) {
+	type task struct {
+		at   int64
+		name string
+	}
+	key := func(x task) int64 { return x.at }
+	q := NewQueue[task]()
+	for _, x := range []task{{5, "a"}, {1, "b"}, {9, "c"}, {5, "d"}, {0, "e"}, {9, "f"}, {3, "g"}, {5, "h"}, {2, "i"}, {7, "j"}} {
+		q.InsertByKey(x, key)
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Queue is invalid: %v", err)
+	}
+	var got string
+	for !q.IsEmpty() {
+		x, _ := q.PopFront()
+		got += x.name
+	}
+	// Equal keys keep insertion order: a, d, h at 5 and c, f at 9
+	if want := "ebigadhjcf"; got != want {
+		t.Errorf("Expected order %q, got %q", want, got)
+	}
+}
+
+func TestSharesBuffer(t *testing.T) {
+	q := Of(1, 2, 3)
+	if !SharesBuffer(q, q) {
+		t.Errorf("Expected a queue to share its own buffer")
+	}
+	alias := *q
+	if !SharesBuffer(q, &alias) {
+		t.Errorf("Expected a shallow struct copy to share the buffer")
+	}
+	if SharesBuffer(q, q.clone()) {
+		t.Errorf("Expected a clone to have its own buffer")
+	}
+	if SharesBuffer(q, q.SubQueue(0, 3)) {
+		t.Errorf("Expected SubQueue to have its own buffer")
+	}
+	if SharesBuffer(q, &Queue[int]{}) {
+		t.Errorf("Expected a queue without a buffer to share nothing")
+	}
+}
+
+func TestNext(t *testing.T) {
+	q := NewQueue[int]()
+	if _, ok := q.Next(); ok {
+		t.Errorf("Expected Next on an empty queue to return false")
+	}
+
+	// Partly and completely filled buffers take different rotation paths
+	for _, n := range []int{3, minCapacity} {
+		q := NewQueue[int]()
+		for i := 0; i < n; i++ {
+			q.PushBack(i)
+		}
+		for i := 0; i < 3*n; i++ {
+			if val, ok := q.Next(); !ok || val != i%n {
+				t.Errorf("n=%d: expected Next to return %d, got (%d, %v)", n, i%n, val, ok)
+			}
+		}
+		if q.Len() != n || len(q.buf) != minCapacity {
+			t.Errorf("n=%d: expected length %d and capacity %d, got %d and %d", n, n, minCapacity, q.Len(), len(q.buf))
+		}
+		if err := q.Validate(); err != nil {
+			t.Errorf("n=%d: queue is invalid: %v", n, err)
+		}
+	}
+}
+
+func TestTransformFrontN(t *testing.T) {
+	q := Of(1, 2, 3, 4, 5)
+	double := func(v int) int { return v * 2 }
+	if n := q.TransformFrontN(2, double); n != 2 {
+		t.Errorf("Expected 2 elements processed, got %d", n)
+	}
+	if !EqualSlice(q, []int{3, 4, 5, 2, 4}) {
+		t.Errorf("Expected [3 4 5 2 4], got %v", q)
+	}
+	if n := q.TransformFrontN(10, double); n != 5 {
+		t.Errorf("Expected n to be capped at 5, got %d", n)
+	}
+	if !EqualSlice(q, []int{6, 8, 10, 4, 8}) {
+		t.Errorf("Expected [6 8 10 4 8], got %v", q)
+	}
+	if n := q.TransformFrontN(-1, double); n != 0 || !EqualSlice(q, []int{6, 8, 10, 4, 8}) {
+		t.Errorf("Expected a negative n to do nothing, got %d and %v", n, q)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid: %v", err)
+	}
+	if n := NewQueue[int]().TransformFrontN(3, double); n != 0 {
+		t.Errorf("Expected 0 on an empty queue, got %d", n)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	q := Of(1, 2, 3)
+	err := q.Transaction(func(q *Queue[int]) error {
+		q.PopFront()
+		q.PushBack(4)
+		return nil
+	})
+	if err != nil || !EqualSlice(q, []int{2, 3, 4}) {
+		t.Errorf("Expected a committed [2 3 4], got %v (%v)", q, err)
+	}
+
+	// An error rolls back every change, including growth
+	errAbort := errors.New("abort")
+	err = q.Transaction(func(q *Queue[int]) error {
+		for i := 0; i < 20; i++ {
+			q.PushFront(i)
+		}
+		q.PopBack()
+		return errAbort
+	})
+	if err != errAbort {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+	if !EqualSlice(q, []int{2, 3, 4}) || len(q.buf) != minCapacity {
+		t.Errorf("Expected [2 3 4] in the original buffer after rollback, got %v with capacity %d", q, len(q.buf))
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid after rollback: %v", err)
+	}
+
+	// A panic rolls back and keeps panicking
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected the panic to propagate, got %v", r)
+			}
+		}()
+		q.Transaction(func(q *Queue[int]) error {
+			q.PushBack(5)
+			panic("boom")
+		})
+	}()
+	if !EqualSlice(q, []int{2, 3, 4}) {
+		t.Errorf("Expected [2 3 4] after a panic, got %v", q)
+	}
+
+	// Iterators taken inside a rolled-back transaction are invalidated
+	var it *Iterator[int]
+	q.Transaction(func(q *Queue[int]) error {
+		it = q.Iterator()
+		return errAbort
+	})
+	expectModifiedPanic(t, func() { it.Next() })
+}
+
+func TestOnFirstElement(t *testing.T) {
+	q := NewQueue[int]()
+	q.PushBack(0) // no callback registered yet
+	q.PopFront()
+
+	calls := 0
+	q.OnFirstElement(func() { calls++ })
+	q.PushBack(1)
+	q.PushBack(2)
+	q.PushFront(0)
+	if calls != 1 {
+		t.Errorf("Expected 1 call after the first push, got %d", calls)
+	}
+	q.PopFront()
+	q.PopFront()
+	if calls != 1 {
+		t.Errorf("Expected pops not to fire the callback, got %d calls", calls)
+	}
+	q.PopFront()
+	q.PushFront(3)
+	if calls != 2 {
+		t.Errorf("Expected PushFront on an empty queue to fire again, got %d calls", calls)
+	}
+
+	// The callback sees the new element
+	q.PopFront()
+	q.OnFirstElement(func() {
+		if val, _ := q.PeekFront(); val != 7 {
+			t.Errorf("Expected the callback to see 7 at the front, got %d", val)
+		}
+	})
+	q.PushBack(7)
+
+	q.OnFirstElement(nil)
+	q.PopFront()
+	q.PushBack(8)
+}
+
+func TestOnFirstElementBulk(t *testing.T) {
+	key := func(v int) int64 { return int64(v) }
+	for name, fill := range map[string]func(q *Queue[int]){
+		"PushBackSlice": func(q *Queue[int]) { q.PushBackSlice([]int{1, 2, 3}, 0, 3) },
+		"InsertAtAll":   func(q *Queue[int]) { q.InsertAtAll(0, 1, 2) },
+		"InsertByKey":   func(q *Queue[int]) { q.InsertByKey(1, key) },
+		"SetContents":   func(q *Queue[int]) { q.SetContents([]int{1, 2}) },
+	} {
+		q := NewQueue[int]()
+		calls := 0
+		q.OnFirstElement(func() { calls++ })
+		fill(q)
+		if calls != 1 {
+			t.Errorf("%s: expected 1 call on an empty queue, got %d", name, calls)
+		}
+		fill(q)
+		if calls != 1 {
+			t.Errorf("%s: expected no call on a non-empty queue, got %d", name, calls)
+		}
+	}
+
+	// Empty insertions do not count
+	q := NewQueue[int]()
+	calls := 0
+	q.OnFirstElement(func() { calls++ })
+	q.InsertAtAll(0)
+	q.PushBackSlice(nil, 0, 0)
+	q.SetContents(nil)
+	if calls != 0 {
+		t.Errorf("Expected no calls for empty insertions, got %d", calls)
+	}
+}
+
+func TestDrainTo(t *testing.T) {
+	// [0 1 ... 99] after growing; drained in chunks of 32
+	q := NewQueue[int]()
+	for i := 0; i < 100; i++ {
+		q.PushBack(i)
+	}
+	scratch := make([]int, 32)
+	next := 0
+	for !q.IsEmpty() {
+		n := q.DrainTo(scratch)
+		if want := min(32, 100-next); n != want {
+			t.Fatalf("Expected to drain %d, got %d", want, n)
+		}
+		for _, v := range scratch[:n] {
+			if v != next {
+				t.Errorf("Expected %d, got %d", next, v)
+			}
+			next++
+		}
+		if err := q.Validate(); err != nil {
+			t.Fatalf("Queue is invalid: %v", err)
+		}
+	}
+	if n := q.DrainTo(scratch); n != 0 {
+		t.Errorf("Expected 0 from an empty queue, got %d", n)
+	}
+
+	// One large drain shrinks the buffer once
+	for i := 0; i < 100; i++ {
+		q.PushBack(i)
+	}
+	if n := q.DrainTo(make([]int, 80)); n != 80 || len(q.buf) != 64 {
+		t.Errorf("Expected 80 drained and capacity 64, got %d and %d", n, len(q.buf))
+	}
+
+	// Wrapped contents and zeroed slots
+	p := NewQueue[*Data]()
+	for i := 0; i < 5; i++ {
+		p.PushFront(&Data{i, "x"})
+	}
+	dst := make([]*Data, 3)
+	if n := p.DrainTo(dst); n != 3 || dst[0].ID != 4 || dst[2].ID != 2 {
+		t.Errorf("Expected IDs 4..2 drained, got %d elements", n)
+	}
+	for i := range p.buf {
+		if p.buf[i] != nil && p.buf[i].ID > 1 {
+			t.Errorf("Expected drained slot %d to be zeroed, got %v", i, p.buf[i])
+		}
+	}
+	if p.Len() != 2 {
+		t.Errorf("Expected 2 elements left, got %d", p.Len())
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	got := q.ToSlice()
+	if len(got) != 10 {
+		t.Fatalf("Expected 10 elements, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != 9-i {
+			t.Errorf("Expected element %d to be %d, got %d", i, 9-i, v)
+		}
+	}
+	got[0] = -1
+	if val, _ := q.PeekFront(); val != 9 {
+		t.Errorf("Expected the queue to be unmodified, front is %d", val)
+	}
+	if got := NewQueue[int]().ToSlice(); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty non-nil slice, got %v", got)
+	}
+}
+
+func TestToReversedSlice(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	got := q.ToReversedSlice()
+	if len(got) != 10 {
+		t.Fatalf("Expected 10 elements, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Errorf("Expected element %d to be %d, got %d", i, i, v)
+		}
+	}
+	got[0] = -1
+	if val, _ := q.PeekBack(); val != 0 {
+		t.Errorf("Expected the queue to be unmodified, back is %d", val)
+	}
+	if got := NewQueue[int]().ToReversedSlice(); len(got) != 0 {
+		t.Errorf("Expected an empty slice, got %v", got)
+	}
+}
+
+func TestSetShrinkRatio(t *testing.T) {
+	fill := func(q *Queue[int], n int) {
+		for i := 0; i < n; i++ {
+			q.PushBack(i)
+		}
+	}
+	popTo := func(q *Queue[int], n int) {
+		for q.Len() > n {
+			q.PopFront()
+		}
+	}
+
+	// Default: halve at a quarter full
+	q := NewQueue[int]()
+	fill(q, 128)
+	popTo(q, 33)
+	if len(q.buf) != 128 {
+		t.Errorf("Expected capacity 128 above the default ratio, got %d", len(q.buf))
+	}
+	popTo(q, 32)
+	if len(q.buf) != 64 {
+		t.Errorf("Expected capacity 64 at the default ratio, got %d", len(q.buf))
+	}
+
+	// A lower ratio waits longer
+	q = NewQueue[int]()
+	q.SetShrinkRatio(0.125)
+	fill(q, 128)
+	popTo(q, 17)
+	if len(q.buf) != 128 {
+		t.Errorf("Expected capacity 128 above ratio 0.125, got %d", len(q.buf))
+	}
+	popTo(q, 16)
+	if len(q.buf) != 64 {
+		t.Errorf("Expected capacity 64 at ratio 0.125, got %d", len(q.buf))
+	}
+
+	// Bulk removals compact using the ratio too
+	q.SetShrinkRatio(0.4)
+	fill(q, 112)
+	q.RetainFirst(40)
+	if len(q.buf) != 64 {
+		t.Errorf("Expected capacity 64 after compacting at ratio 0.4, got %d", len(q.buf))
+	}
+	q.RetainFirst(25)
+	if len(q.buf) != 32 {
+		t.Errorf("Expected capacity 32 after compacting at ratio 0.4, got %d", len(q.buf))
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid: %v", err)
+	}
+
+	// Zero disables shrinking; invalid ratios are ignored
+	q = NewQueue[int]()
+	q.SetShrinkRatio(0)
+	for _, r := range []float64{-1, 0.5, 2} {
+		q.SetShrinkRatio(r)
+	}
+	fill(q, 128)
+	popTo(q, 0)
+	q.PushBack(1)
+	q.RetainFirst(0)
+	if len(q.buf) != 128 {
+		t.Errorf("Expected capacity 128 with shrinking disabled, got %d", len(q.buf))
+	}
+
+	// The setting carries over to clones
+	c := q.clone()
+	fill(c, 128)
+	popTo(c, 0)
+	if len(c.buf) != 128 {
+		t.Errorf("Expected a clone to keep shrinking disabled, got capacity %d", len(c.buf))
+	}
+}
+
+func TestPopAll(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	even := func(v int) bool { return v%2 == 0 }
+	got := q.PopAll(even)
+	if len(got) != 5 || got[0] != 8 || got[4] != 0 {
+		t.Errorf("Expected [8 6 4 2 0], got %v", got)
+	}
+	if !EqualSlice(q, []int{9, 7, 5, 3, 1}) {
+		t.Errorf("Expected [9 7 5 3 1] to remain, got %v", q)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid: %v", err)
+	}
+	if got := q.PopAll(even); got != nil {
+		t.Errorf("Expected nil when nothing matches, got %v", got)
+	}
+	if got := q.PopAll(func(int) bool { return true }); len(got) != 5 || !q.IsEmpty() {
+		t.Errorf("Expected every element popped, got %v and %d left", got, q.Len())
+	}
+}
+
+func TestCallbacksFailFast(t *testing.T) {
+	fill := func() *Queue[int] { return Of(1, 2, 3, 4, 5, 6) }
+
+	q := fill()
+	expectModifiedPanic(t, func() {
+		q.ScanPairs(func(left, right int) bool { q.PopBack(); return true })
+	})
+	q = fill()
+	expectModifiedPanic(t, func() {
+		q.ForEachChunk(2, func([]int) { q.PushBack(0) })
+	})
+	q = fill()
+	expectModifiedPanic(t, func() {
+		q.Apply(func(*int) { q.PopFront() })
+	})
+	q = fill()
+	expectModifiedPanic(t, func() {
+		q.ReplaceFunc(func(v int) int { q.PushFront(v); return v })
+	})
+
+	// Mutating the elements themselves is not a modification of the queue
+	q = fill()
+	q.Apply(func(p *int) { *p *= 2 })
+	q.ReplaceFunc(func(v int) int { return v + 1 })
+	if !EqualSlice(q, []int{3, 5, 7, 9, 11, 13}) {
+		t.Errorf("Expected [3 5 7 9 11 13], got %v", q)
+	}
+
+	// With fail-fast disabled, ScanPairs follows the live back
+	q = fill()
+	q.SetFailFast(false)
+	var rights []int
+	q.ScanPairs(func(left, right int) bool {
+		rights = append(rights, right)
+		q.PopBack()
+		q.PopBack()
+		return true
+	})
+	if fmt.Sprint(rights) != "[6 4]" {
+		t.Errorf("Expected ScanPairs to visit only live elements [6 4], got %v", rights)
+	}
+}
@@ -0,0 +1,43 @@
+package bfq
+
+import "iter"
+
+// ReadOnlyQueue is a view of a Queue that exposes only reading methods, for
+// handing a queue across an API boundary that must not modify it. The view
+// shares the queue's buffer, so it reflects later changes made through the
+// queue itself.
+type ReadOnlyQueue[T any] struct {
+	q *Queue[T]
+}
+
+// ReadOnly returns a read-only view of the queue.
+func (q *Queue[T]) ReadOnly() ReadOnlyQueue[T] {
+	return ReadOnlyQueue[T]{q: q}
+}
+
+// Len returns the number of elements in the queue.
+func (r ReadOnlyQueue[T]) Len() int { return r.q.Len() }
+
+// IsEmpty checks if the queue is empty.
+func (r ReadOnlyQueue[T]) IsEmpty() bool { return r.q.IsEmpty() }
+
+// At returns the element at logical index i, or false if i is out of range.
+func (r ReadOnlyQueue[T]) At(i int) (T, bool) { return r.q.At(i) }
+
+// Front returns the front element without removing it.
+func (r ReadOnlyQueue[T]) Front() (T, bool) { return r.q.Front() }
+
+// Back returns the back element without removing it.
+func (r ReadOnlyQueue[T]) Back() (T, bool) { return r.q.Back() }
+
+// ToSlice returns a copy of the elements in front-to-back order.
+func (r ReadOnlyQueue[T]) ToSlice() []T { return r.q.ToSlice() }
+
+// Iterator returns an iterator positioned at the front of the queue.
+func (r ReadOnlyQueue[T]) Iterator() *Iterator[T] { return r.q.Iterator() }
+
+// Reversed returns an iterator over the elements from back to front.
+func (r ReadOnlyQueue[T]) Reversed() iter.Seq[T] { return r.q.Reversed() }
+
+// String returns a string representation of the queue.
+func (r ReadOnlyQueue[T]) String() string { return r.q.String() }
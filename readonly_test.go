@@ -0,0 +1,57 @@
+package bfq
+
+import "testing"
+
+func TestReadOnly(t *testing.T) {
+	// [9 8 ... 0] wraps around the end of the buffer
+	q := NewQueue[int]()
+	for i := 0; i < 10; i++ {
+		q.PushFront(i)
+	}
+	r := q.ReadOnly()
+	if r.Len() != 10 || r.IsEmpty() {
+		t.Errorf("Expected length 10, got %d", r.Len())
+	}
+	if val, ok := r.At(3); !ok || val != 6 {
+		t.Errorf("Expected At(3) to be 6, got (%d, %v)", val, ok)
+	}
+	for _, i := range []int{-1, 10} {
+		if _, ok := r.At(i); ok {
+			t.Errorf("Expected At(%d) to be out of range", i)
+		}
+	}
+	if f, _ := r.Front(); f != 9 {
+		t.Errorf("Expected front 9, got %d", f)
+	}
+	if b, _ := r.Back(); b != 0 {
+		t.Errorf("Expected back 0, got %d", b)
+	}
+	s := r.ToSlice()
+	if len(s) != 10 || s[0] != 9 || s[9] != 0 {
+		t.Errorf("Expected [9 ... 0], got %v", s)
+	}
+	s[0] = -1
+	if f, _ := q.Front(); f != 9 {
+		t.Errorf("Expected ToSlice to return a copy, front is now %d", f)
+	}
+
+	sum := 0
+	for it := r.Iterator(); it.HasNext(); {
+		sum += it.Next()
+	}
+	for v := range r.Reversed() {
+		sum += v
+	}
+	if sum != 90 {
+		t.Errorf("Expected both iterators to visit every element, got sum %d", sum)
+	}
+	if r.String() != q.String() {
+		t.Errorf("Expected %s, got %s", q, r)
+	}
+
+	// The view follows changes made through the queue
+	q.PushBack(-5)
+	if b, _ := r.Back(); b != -5 || r.Len() != 11 {
+		t.Errorf("Expected the view to see the new back -5, got %d", b)
+	}
+}
@@ -0,0 +1,75 @@
+package bfq
+
+import (
+	"iter"
+	"slices"
+)
+
+// Collect drains seq into a new queue, in yield order.
+func Collect[T any](seq iter.Seq[T]) *Queue[T] {
+	q := NewQueue[T]()
+	q.AppendSeq(seq)
+	return q
+}
+
+// maxSizeHint caps the preallocation FromSeq makes from a size hint, so an
+// overstated hint cannot exhaust memory before anything has been yielded.
+const maxSizeHint = 1 << 20
+
+// FromSeq drains seq into a new queue like Collect, preallocating room for
+// sizeHint elements to avoid repeated resizes when the sequence length is
+// roughly known. The preallocation is capped at maxSizeHint elements and
+// the queue grows normally beyond it; a negative hint is treated as no hint.
+func FromSeq[T any](seq iter.Seq[T], sizeHint int) *Queue[T] {
+	q, err := NewQueueWithCapacity[T](min(sizeHint, maxSizeHint))
+	if err != nil {
+		q = NewQueue[T]()
+	}
+	q.AppendSeq(seq)
+	return q
+}
+
+// AppendSeq pushes every element of seq to the back of the queue, in yield
+// order. The buffer grows by the queue's growth factor as elements arrive.
+func (q *Queue[T]) AppendSeq(seq iter.Seq[T]) {
+	for v := range seq {
+		q.PushBack(v)
+	}
+}
+
+// PushFrontSeq prepends every element of seq while keeping its yield
+// order: the first element yielded becomes the front, followed by the rest
+// in order, then the previous contents. The sequence is buffered in full
+// before the queue is touched, so seq may safely iterate q itself.
+func (q *Queue[T]) PushFrontSeq(seq iter.Seq[T]) {
+	q.InsertAtAll(0, slices.Collect(seq)...)
+}
+
+// Indices returns an iterator over the logical indices 0..Len()-1, front to
+// back, for use with At and Set. The length is re-read on every step, so
+// removing elements during iteration ends it early rather than yielding
+// stale indices.
+func (q *Queue[T]) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < q.length; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Reversed returns an iterator over the values from back to front, without
+// modifying the queue. It panics if the queue is modified between yields,
+// unless fail-fast iteration has been disabled with SetFailFast.
+func (q *Queue[T]) Reversed() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		version := q.version
+		for i := q.length - 1; i >= 0; i = min(i-1, q.length-1) {
+			if !yield(*q.at(i)) {
+				return
+			}
+			q.checkVersion(version)
+		}
+	}
+}
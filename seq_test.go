@@ -0,0 +1,189 @@
+package bfq
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	q := Collect(slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+	if q.Len() != 10 {
+		t.Errorf("Expected queue length 10, got %d", q.Len())
+	}
+	for i := 1; i <= 10; i++ {
+		if val, ok := q.PopFront(); !ok || val != i {
+			t.Errorf("Expected popped value %d, got %v", i, val)
+		}
+	}
+}
+
+func TestCollectMapValues(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	q := Collect(maps.Values(m))
+	sum := 0
+	for !q.IsEmpty() {
+		v, _ := q.PopFront()
+		sum += v
+	}
+	if sum != 6 {
+		t.Errorf("Expected values to sum to 6, got %d", sum)
+	}
+}
+
+func TestCollectEmpty(t *testing.T) {
+	if q := Collect(slices.Values([]int(nil))); !q.IsEmpty() {
+		t.Errorf("Expected queue to be empty, but it's not")
+	}
+}
+
+func TestAppendSeq(t *testing.T) {
+	q := NewQueue[int]()
+	q.PushFront(0)
+	q.AppendSeq(slices.Values([]int{1, 2, 3}))
+	q.AppendSeq(slices.Values([]int{4, 5, 6, 7, 8, 9}))
+
+	if s := q.String(); s != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Errorf("Expected [0 1 2 3 4 5 6 7 8 9], got %s", s)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Unexpected invalid state: %v", err)
+	}
+}
+
+func TestIndices(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushFront(i)
+	}
+
+	got := slices.Collect(q.Indices())
+	if !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected indices [0 1 2 3 4], got %v", got)
+	}
+
+	// Breaking out early stops the iteration
+	n := 0
+	for i := range q.Indices() {
+		if i == 2 {
+			break
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 indices before break, got %d", n)
+	}
+
+	if got := slices.Collect(NewQueue[int]().Indices()); len(got) != 0 {
+		t.Errorf("Expected no indices for empty queue, got %v", got)
+	}
+}
+
+func TestReversed(t *testing.T) {
+	q := NewQueue[int]()
+	for i := 0; i < 5; i++ {
+		q.PushFront(i)
+		q.PushBack(i + 10)
+	}
+
+	got := slices.Collect(q.Reversed())
+	want := []int{14, 13, 12, 11, 10, 0, 1, 2, 3, 4}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if q.Len() != 10 {
+		t.Errorf("Expected queue length 10, got %d", q.Len())
+	}
+
+	for v := range q.Reversed() {
+		if v != 14 {
+			t.Errorf("Expected first value 14, got %v", v)
+		}
+		break
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+
+	q := FromSeq(slices.Values(items), len(items))
+	if len(q.buf) != 1024 {
+		t.Errorf("Expected preallocated buffer of 1024, got %d", len(q.buf))
+	}
+	if !EqualSlice(q, items) {
+		t.Errorf("Expected the sequence in order")
+	}
+
+	// An undersized, oversized or invalid hint still collects everything
+	for _, hint := range []int{-5, 0, 10, 1 << 40} {
+		q := FromSeq(slices.Values(items), hint)
+		if !EqualSlice(q, items) {
+			t.Errorf("Expected the sequence in order with hint %d", hint)
+		}
+		if len(q.buf) > maxSizeHint {
+			t.Errorf("Expected the preallocation capped at %d with hint %d, got %d", maxSizeHint, hint, len(q.buf))
+		}
+	}
+}
+
+func BenchmarkFromSeq(b *testing.B) {
+	items := make([]int, 100000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		FromSeq(slices.Values(items), len(items))
+	}
+}
+
+func TestPushFrontSeq(t *testing.T) {
+	q := Of(10, 11, 12)
+	q.PushFrontSeq(slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8}))
+	if !EqualSlice(q, []int{1, 2, 3, 4, 5, 6, 7, 8, 10, 11, 12}) {
+		t.Errorf("Expected [1 ... 8 10 11 12], got %v", q)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Queue is invalid: %v", err)
+	}
+
+	// A queue can prepend its own reversed contents
+	p := Of(1, 2, 3)
+	p.PushFrontSeq(p.Reversed())
+	if !EqualSlice(p, []int{3, 2, 1, 1, 2, 3}) {
+		t.Errorf("Expected [3 2 1 1 2 3], got %v", p)
+	}
+
+	p.PushFrontSeq(slices.Values([]int(nil)))
+	if p.Len() != 6 {
+		t.Errorf("Expected an empty sequence to change nothing, got length %d", p.Len())
+	}
+
+	// The prepended end becomes the last pushed
+	p.PushBack(4)
+	p.PushFrontSeq(slices.Values([]int{0}))
+	if v, _ := p.LastPushed(); v != 0 {
+		t.Errorf("Expected last pushed 0, got %d", v)
+	}
+
+	// Filling an empty queue wakes an OnFirstElement consumer
+	e := NewQueue[int]()
+	calls := 0
+	e.OnFirstElement(func() { calls++ })
+	e.PushFrontSeq(slices.Values([]int{1, 2}))
+	if calls != 1 {
+		t.Errorf("Expected 1 OnFirstElement call, got %d", calls)
+	}
+}
+
+func TestIndicesWithSet(t *testing.T) {
+	q := Of(1, 2, 3, 4, 5)
+	for i := range q.Indices() {
+		if v, _ := q.At(i); v%2 == 0 {
+			q.Set(i, -v)
+		}
+	}
+	if !EqualSlice(q, []int{1, -2, 3, -4, 5}) {
+		t.Errorf("Expected [1 -2 3 -4 5], got %v", q)
+	}
+}
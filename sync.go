@@ -0,0 +1,102 @@
+package bfq
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SyncQueue is a Queue guarded by a mutex, safe for concurrent use.
+type SyncQueue[T any] struct {
+	mu sync.Mutex
+	q  *Queue[T]
+	// n mirrors q.Len() so it can be read without taking the lock.
+	n atomic.Int64
+}
+
+// NewSyncQueue creates an empty queue that is safe for concurrent use.
+func NewSyncQueue[T any]() *SyncQueue[T] {
+	return &SyncQueue[T]{q: NewQueue[T]()}
+}
+
+// Len returns the number of elements in the queue.
+func (q *SyncQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.q.Len()
+}
+
+// LenApprox returns the number of elements without taking the lock. It is a
+// best-effort snapshot for metrics and may be momentarily stale while other
+// goroutines push or pop.
+func (q *SyncQueue[T]) LenApprox() int { return int(q.n.Load()) }
+
+// unlock publishes the current length for LenApprox and releases the lock.
+// Every mutating method must release the lock through it, deferred so that
+// a panic such as "bfq: queue too large" does not leave the lock held.
+func (q *SyncQueue[T]) unlock() {
+	q.n.Store(int64(q.q.length))
+	q.mu.Unlock()
+}
+
+// PushFront inserts an element at the front.
+func (q *SyncQueue[T]) PushFront(v T) {
+	q.mu.Lock()
+	defer q.unlock()
+	q.q.PushFront(v)
+}
+
+// PushBack inserts an element at the back.
+func (q *SyncQueue[T]) PushBack(v T) {
+	q.mu.Lock()
+	defer q.unlock()
+	q.q.PushBack(v)
+}
+
+// PushBackAll inserts items at the back while holding the lock once, so the
+// batch lands contiguously and is never interleaved with other producers.
+func (q *SyncQueue[T]) PushBackAll(items ...T) {
+	q.mu.Lock()
+	defer q.unlock()
+	q.q.appendSlice(items)
+}
+
+// PopFront removes and returns the front element.
+func (q *SyncQueue[T]) PopFront() (T, bool) {
+	q.mu.Lock()
+	defer q.unlock()
+	return q.q.PopFront()
+}
+
+// PopBack removes and returns the back element.
+func (q *SyncQueue[T]) PopBack() (T, bool) {
+	q.mu.Lock()
+	defer q.unlock()
+	return q.q.PopBack()
+}
+
+// PopFrontIf removes and returns the front element only if it satisfies
+// pred, avoiding the race between a separate peek and pop. It returns false
+// if the queue is empty or the front did not match. pred runs while the
+// lock is held and must not call back into the queue.
+func (q *SyncQueue[T]) PopFrontIf(pred func(T) bool) (T, bool) {
+	q.mu.Lock()
+	defer q.unlock()
+	if v, ok := q.q.Front(); !ok || !pred(v) {
+		var zero T
+		return zero, false
+	}
+	return q.q.PopFront()
+}
+
+// PopFrontN removes and returns up to n elements from the front in logical
+// order, holding the lock once for the whole batch.
+func (q *SyncQueue[T]) PopFrontN(n int) []T {
+	q.mu.Lock()
+	defer q.unlock()
+	n = min(max(n, 0), q.q.length)
+	items := make([]T, n)
+	q.q.copyTo(items)
+	q.q.discardFront(n)
+	q.q.compact()
+	return items
+}
@@ -0,0 +1,260 @@
+package bfq
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncQueueConcurrentPush(t *testing.T) {
+	q := NewSyncQueue[int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				q.PushBack(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if q.Len() != 8000 {
+		t.Errorf("Expected queue length 8000, got %d", q.Len())
+	}
+}
+
+func TestSyncQueuePopFrontIf(t *testing.T) {
+	q := NewSyncQueue[int]()
+	if _, ok := q.PopFrontIf(func(int) bool { return true }); ok {
+		t.Errorf("Expected PopFrontIf to return false on empty queue")
+	}
+
+	q.PushBack(1)
+	q.PushBack(2)
+
+	// A non-matching front stays in place
+	if _, ok := q.PopFrontIf(func(v int) bool { return v > 1 }); ok {
+		t.Errorf("Expected PopFrontIf to return false for non-matching front")
+	}
+	if q.Len() != 2 {
+		t.Errorf("Expected queue length 2, got %d", q.Len())
+	}
+
+	if val, ok := q.PopFrontIf(func(v int) bool { return v == 1 }); !ok || val != 1 {
+		t.Errorf("Expected popped value 1, got %v", val)
+	}
+	if val, ok := q.PopFront(); !ok || val != 2 {
+		t.Errorf("Expected popped value 2, got %v", val)
+	}
+}
+
+func TestSyncQueuePopFrontIfConcurrent(t *testing.T) {
+	q := NewSyncQueue[int]()
+	for i := 0; i < 1000; i++ {
+		q.PushBack(i)
+	}
+
+	// Every element must be claimed exactly once
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := q.PopFrontIf(func(int) bool { return true })
+				if !ok {
+					return
+				}
+				mu.Lock()
+				seen[v]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 1000 {
+		t.Errorf("Expected 1000 distinct elements, got %d", len(seen))
+	}
+	for v, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected element %d to be popped once, got %d", v, n)
+		}
+	}
+}
+
+func TestSyncQueuePopFrontN(t *testing.T) {
+	q := NewSyncQueue[int]()
+	for i := 0; i < 20; i++ {
+		q.PushBack(i)
+	}
+
+	items := q.PopFrontN(8)
+	if len(items) != 8 {
+		t.Fatalf("Expected 8 items, got %d", len(items))
+	}
+	for i, v := range items {
+		if v != i {
+			t.Errorf("Expected item %d, got %v", i, v)
+		}
+	}
+
+	// Asking for more than available returns the rest
+	items = q.PopFrontN(100)
+	if len(items) != 12 || items[0] != 8 || items[11] != 19 {
+		t.Errorf("Expected items [8..19], got %v", items)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected queue to be empty, got length %d", q.Len())
+	}
+	if items := q.PopFrontN(5); len(items) != 0 {
+		t.Errorf("Expected no items from empty queue, got %v", items)
+	}
+}
+
+func BenchmarkSyncQueuePopFront(b *testing.B) {
+	q := NewSyncQueue[int]()
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			q.PopFront()
+		}
+	})
+}
+
+func BenchmarkSyncQueuePopFrontN(b *testing.B) {
+	const batch = 64
+	q := NewSyncQueue[int]()
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		// Each batch accounts for batch iterations
+		for n := 0; pb.Next(); n++ {
+			if n%batch == 0 {
+				q.PopFrontN(batch)
+			}
+		}
+	})
+}
+
+func TestSyncQueueLenApprox(t *testing.T) {
+	q := NewSyncQueue[int]()
+	if n := q.LenApprox(); n != 0 {
+		t.Errorf("Expected approximate length 0, got %d", n)
+	}
+
+	for i := 0; i < 20; i++ {
+		q.PushBack(i)
+	}
+	q.PushFront(-1)
+	q.PopBack()
+	q.PopFrontIf(func(v int) bool { return v < 0 })
+	q.PopFrontN(5)
+	if n, want := q.LenApprox(), q.Len(); n != want || n != 14 {
+		t.Errorf("Expected approximate length %d, got %d", want, n)
+	}
+}
+
+func TestSyncQueueLenApproxConcurrent(t *testing.T) {
+	q := NewSyncQueue[int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				q.PushBack(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if n := q.LenApprox(); n < 0 {
+					t.Errorf("Unexpected negative length %d", n)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := q.LenApprox(); n != 4000 {
+		t.Errorf("Expected approximate length 4000, got %d", n)
+	}
+}
+
+func TestSyncQueuePushBackAllContiguous(t *testing.T) {
+	const producers, batch = 8, 100
+	q := NewSyncQueue[int]()
+	var wg sync.WaitGroup
+	for g := 0; g < producers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			items := make([]int, batch)
+			for i := range items {
+				items[i] = g*batch + i
+			}
+			q.PushBackAll(items...)
+		}(g)
+	}
+	wg.Wait()
+
+	if q.LenApprox() != producers*batch {
+		t.Fatalf("Expected queue length %d, got %d", producers*batch, q.LenApprox())
+	}
+
+	// Each batch must appear as one contiguous, ordered run
+	for b := 0; b < producers; b++ {
+		items := q.PopFrontN(batch)
+		start := items[0]
+		if start%batch != 0 {
+			t.Fatalf("Expected batch to start on a multiple of %d, got %d", batch, start)
+		}
+		for i, v := range items {
+			if v != start+i {
+				t.Errorf("Expected value %d in batch, got %d", start+i, v)
+			}
+		}
+	}
+}
+
+func TestSyncQueuePanicReleasesLock(t *testing.T) {
+	defer func(old int) { maxSize = old }(maxSize)
+	maxSize = 32
+
+	q := NewSyncQueue[int]()
+	func() {
+		defer func() {
+			if r := recover(); r != "bfq: queue too large" {
+				t.Errorf("Expected \"queue too large\" panic, got %v", r)
+			}
+		}()
+		q.PushBackAll(make([]int, 64)...)
+	}()
+
+	// The lock must have been released by the panicking call
+	done := make(chan struct{})
+	go func() {
+		q.PushBack(1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected PushBack to proceed after a panic, but the lock is still held")
+	}
+	if q.Len() != 1 || q.LenApprox() != 1 {
+		t.Errorf("Expected queue length 1, got %d (approx %d)", q.Len(), q.LenApprox())
+	}
+}
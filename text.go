@@ -0,0 +1,37 @@
+package bfq
+
+import "strings"
+
+// RuneQueueString returns the text formed by the runes of q in order, for
+// using a rune queue as an editable text buffer. String, by contrast,
+// formats the runes as numbers.
+func RuneQueueString(q *Queue[rune]) string {
+	var sb strings.Builder
+	sb.Grow(q.length)
+	for i := 0; i < q.length; i++ {
+		sb.WriteRune(*q.at(i))
+	}
+	return sb.String()
+}
+
+// Bytes returns a copy of the contents of q as a contiguous byte slice, in
+// order. The wraparound is handled with at most two copies.
+func Bytes(q *Queue[byte]) []byte {
+	b := make([]byte, q.length)
+	q.copyTo(b)
+	return b
+}
+
+// BytesString returns the contents of q as a string.
+func BytesString(q *Queue[byte]) string {
+	var sb strings.Builder
+	sb.Grow(q.length)
+	end := q.front + q.length
+	if end <= len(q.buf) {
+		sb.Write(q.buf[q.front:end])
+	} else {
+		sb.Write(q.buf[q.front:])
+		sb.Write(q.buf[:end-len(q.buf)])
+	}
+	return sb.String()
+}
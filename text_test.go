@@ -0,0 +1,56 @@
+package bfq
+
+import "testing"
+
+func TestRuneQueueString(t *testing.T) {
+	q := NewQueue[rune]()
+	if s := RuneQueueString(q); s != "" {
+		t.Errorf("Expected empty string, got %q", s)
+	}
+
+	for _, r := range "мир" {
+		q.PushBack(r)
+	}
+	for _, r := range []rune("привет, ") {
+		q.PushFront(r)
+	}
+	// PushFront reverses the prefix
+	if s := RuneQueueString(q); s != " ,тевирпмир" {
+		t.Errorf("Expected \" ,тевирпмир\", got %q", s)
+	}
+}
+
+func TestBytesAndBytesString(t *testing.T) {
+	q := NewQueue[byte]()
+	if b := Bytes(q); len(b) != 0 {
+		t.Errorf("Expected no bytes, got %q", b)
+	}
+
+	for _, c := range []byte("world") {
+		q.PushBack(c)
+	}
+	for _, c := range []byte(" olleh") {
+		q.PushFront(c)
+	}
+
+	b := Bytes(q)
+	if string(b) != "hello world" {
+		t.Errorf("Expected \"hello world\", got %q", b)
+	}
+	if s := BytesString(q); s != "hello world" {
+		t.Errorf("Expected \"hello world\", got %q", s)
+	}
+
+	// The returned slice is a copy
+	b[0] = 'H'
+	if front, _ := q.Front(); front != 'h' {
+		t.Errorf("Expected queue to be unaffected, got front %q", front)
+	}
+
+	// ByteQueue embeds a Queue[byte]
+	bq := NewByteQueue()
+	bq.Write([]byte("abc"))
+	if s := BytesString(&bq.Queue); s != "abc" {
+		t.Errorf("Expected \"abc\", got %q", s)
+	}
+}